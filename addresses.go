@@ -31,15 +31,16 @@ func (ipaddress *IPAddress) Get(ctx context.Context, client *Client) error {
 		return err
 	}
 
-	ips := resp.(*ListPublicIPAddressesResponse)
+	ips, ok := resp.(*ListPublicIPAddressesResponse)
+	if !ok {
+		return &UnexpectedResponseError{Command: req.APIName(), Response: resp}
+	}
+
 	count := len(ips.PublicIPAddress)
 	if count == 0 {
-		return &ErrorResponse{
-			ErrorCode: ParamError,
-			ErrorText: fmt.Sprintf("PublicIPAddress not found. id: %s, ipaddress: %s", ipaddress.ID, ipaddress.IPAddress),
-		}
+		return notFoundError("PublicIPAddress not found. id: %s, ipaddress: %s", ipaddress.ID, ipaddress.IPAddress)
 	} else if count > 1 {
-		return fmt.Errorf("More than one PublicIPAddress was found")
+		return multipleResultsError("More than one PublicIPAddress was found")
 	}
 
 	return copier.Copy(ipaddress, ips.PublicIPAddress[0])
@@ -172,7 +172,7 @@ func TestDeleteIPAddress(t *testing.T) {
 	"jobresult": {
 		"success": true
 	},
-	"jobstatus": 2
+	"jobstatus": 1
 }}`})
 
 	defer ts.Close()
@@ -186,6 +186,29 @@ func TestDeleteIPAddress(t *testing.T) {
 	}
 }
 
+func TestDeleteIPAddressFailed(t *testing.T) {
+	ts := newServer(response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "b1ac7d06-3320-4388-b234-43420bcb236c",
+	"jobprocstatus": 0,
+	"jobresult": {
+		"success": true
+	},
+	"jobstatus": 2
+}}`})
+
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	eip := &IPAddress{
+		ID: "err",
+	}
+	// the job status prevails over the result given right away
+	if err := cs.Delete(eip); err == nil {
+		t.Errorf("An error was expected")
+	}
+}
+
 func TestDeleteIPAddressInvalid(t *testing.T) {
 	ts := newServer(response{400, ``})
 
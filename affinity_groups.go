@@ -36,24 +36,26 @@ func (ag *AffinityGroup) Get(ctx context.Context, client *Client) error {
 		return fmt.Errorf("An Affinity Group may only be searched using ID or Name")
 	}
 
-	resp, err := client.RequestWithContext(ctx, &ListAffinityGroups{
+	req := &ListAffinityGroups{
 		ID:   ag.ID,
 		Name: ag.Name,
-	})
+	}
 
+	resp, err := client.RequestWithContext(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	ags := resp.(*ListAffinityGroupsResponse)
+	ags, ok := resp.(*ListAffinityGroupsResponse)
+	if !ok {
+		return &UnexpectedResponseError{Command: req.APIName(), Response: resp}
+	}
+
 	count := len(ags.AffinityGroup)
 	if count == 0 {
-		return &ErrorResponse{
-			ErrorCode: ParamError,
-			ErrorText: fmt.Sprintf("AffinityGroup not found id: %s, name: %s", ag.ID, ag.Name),
-		}
+		return notFoundError("AffinityGroup not found id: %s, name: %s", ag.ID, ag.Name)
 	} else if count > 1 {
-		return fmt.Errorf("More than one Affinity Group was found. Query; id: %s, name: %s", ag.ID, ag.Name)
+		return multipleResultsError("More than one Affinity Group was found. Query; id: %s, name: %s", ag.ID, ag.Name)
 	}
 
 	return copier.Copy(ag, ags.AffinityGroup[0])
@@ -110,8 +112,8 @@ type CreateAffinityGroupResponse struct {
 // CloudStack API: http://cloudstack.apache.org/api/apidocs-4.10/apis/updateVMAffinityGroup.html
 type UpdateVMAffinityGroup struct {
 	ID                 string   `json:"id"`
-	AffinityGroupIDs   []string `json:"affinitygroupids,omitempty"`   // mutually exclusive with names
-	AffinityGroupNames []string `json:"affinitygroupnames,omitempty"` // mutually exclusive with ids
+	AffinityGroupIDs   []string `json:"affinitygroupids,omitempty" validate:"excludes=AffinityGroupNames"` // mutually exclusive with names
+	AffinityGroupNames []string `json:"affinitygroupnames,omitempty"`                                      // mutually exclusive with ids
 }
 
 // APIName returns the CloudStack API command name
@@ -140,8 +142,8 @@ type UpdateVMAffinityGroupResponse VirtualMachineResponse
 type DeleteAffinityGroup struct {
 	Account   string `json:"account,omitempty"` // must be specified with DomainID
 	DomainID  string `json:"domainid,omitempty"`
-	ID        string `json:"id,omitempty"`   // mutually exclusive with Name
-	Name      string `json:"name,omitempty"` // mutually exclusive with ID
+	ID        string `json:"id,omitempty" validate:"excludes=Name"` // mutually exclusive with Name
+	Name      string `json:"name,omitempty"`                        // mutually exclusive with ID
 	ProjectID string `json:"projectid,omitempty"`
 }
 
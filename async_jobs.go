@@ -1,14 +1,17 @@
 package egoscale
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 )
 
 // AsyncJobResult represents an asynchronous job result
 type AsyncJobResult struct {
 	AccountID       string           `json:"accountid"`
 	Cmd             string           `json:"cmd"`
-	Created         string           `json:"created"`
+	Created         *Timestamp       `json:"created,omitempty"`
 	JobInstanceID   string           `json:"jobinstanceid,omitempty"`
 	JobInstanceType string           `json:"jobinstancetype,omitempty"`
 	JobProcStatus   int              `json:"jobprocstatus"`
@@ -20,6 +23,25 @@ type AsyncJobResult struct {
 	JobID           string           `json:"jobid"`
 }
 
+// AsyncJobError represents an error that occurred while waiting for an async job
+//
+// The job may still be running, its JobID can be used to query it later on.
+type AsyncJobError struct {
+	Command string
+	JobID   string
+	Err     error
+}
+
+// Error formats the error
+func (e *AsyncJobError) Error() string {
+	return fmt.Sprintf("%s (job %s): %s", e.Command, e.JobID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *AsyncJobError) Unwrap() error {
+	return e.Err
+}
+
 // QueryAsyncJobResult represents a query to fetch the status of async job
 //
 // CloudStack API: https://cloudstack.apache.org/api/apidocs-4.10/apis/queryAsyncJobResult.html
@@ -43,13 +65,13 @@ type QueryAsyncJobResultResponse AsyncJobResult
 //
 // CloudStack API: https://cloudstack.apache.org/api/apidocs-4.10/apis/listAsyncJobs.html
 type ListAsyncJobs struct {
-	Account     string `json:"account,omitempty"`
-	DomainID    string `json:"domainid,omitempty"`
-	IsRecursive *bool  `json:"isrecursive,omitempty"`
-	Keyword     string `json:"keyword,omitempty"`
-	Page        int    `json:"page,omitempty"`
-	PageSize    int    `json:"pagesize,omitempty"`
-	StartDate   string `json:"startdate,omitempty"`
+	Account     string     `json:"account,omitempty"`
+	DomainID    string     `json:"domainid,omitempty"`
+	IsRecursive *bool      `json:"isrecursive,omitempty"`
+	Keyword     string     `json:"keyword,omitempty"`
+	Page        int        `json:"page,omitempty"`
+	PageSize    int        `json:"pagesize,omitempty"`
+	StartDate   *Timestamp `json:"startdate,omitempty"`
 }
 
 // APIName returns the CloudStack API command name
@@ -66,3 +88,209 @@ type ListAsyncJobsResponse struct {
 	Count     int              `json:"count"`
 	AsyncJobs []AsyncJobResult `json:"asyncjobs"`
 }
+
+// AsyncJob represents a handle on an asynchronous job
+//
+// It lets one submit an async command without waiting for it to complete and
+// collect its result later on.
+//
+//	job, err := cs.SubmitWithContext(ctx, &egoscale.DeployVirtualMachine{...})
+//	// ...
+//	if err := job.Wait(ctx); err != nil {
+//		// ...
+//	}
+//	vm := new(egoscale.DeployVirtualMachineResponse)
+//	err = job.Result(vm)
+//
+// A handle isn't safe for concurrent use: Poll and Wait update its result
+// without any locking, they must not be called at the same time.
+type AsyncJob struct {
+	// JobID is the CloudStack identifier of the job
+	JobID string
+	// Command is the API name of the command that created the job, empty for a resumed job
+	Command string
+	// Created is when the job was submitted
+	Created time.Time
+
+	client *Client
+	result *AsyncJobResult
+	polls  int
+	dryRun bool
+}
+
+// Submit sends an async command without waiting for the job to complete
+func (exo *Client) Submit(request Command) (*AsyncJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), exo.Timeout)
+	defer cancel()
+
+	return exo.SubmitWithContext(ctx, request)
+}
+
+// SubmitWithContext sends an async command without waiting for the job to complete
+func (exo *Client) SubmitWithContext(ctx context.Context, request Command) (*AsyncJob, error) {
+	if request == nil {
+		return nil, ErrNilCommand
+	}
+
+	req, ok := request.(asyncCommand)
+	if !ok {
+		return nil, &UnsupportedCommandError{Command: request.APIName()}
+	}
+
+	return exo.submit(ctx, req)
+}
+
+// ResumeAsyncJob rebuilds the handle of a previously submitted job from its ID
+func (exo *Client) ResumeAsyncJob(jobID string) *AsyncJob {
+	return &AsyncJob{
+		JobID:  jobID,
+		client: exo,
+	}
+}
+
+// submit sends the async command and builds the job handle
+func (exo *Client) submit(ctx context.Context, request asyncCommand) (*AsyncJob, error) {
+	body, err := exo.request(ctx, request.APIName(), request)
+	if err == ErrDryRun {
+		return exo.dryRunJob(request.APIName()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jobResult := new(AsyncJobResult)
+	if err := json.Unmarshal(body, jobResult); err != nil {
+		r := new(ErrorResponse)
+		if e := json.Unmarshal(body, r); e == nil && r.ErrorCode != 0 {
+			return nil, r
+		}
+		return nil, err
+	}
+
+	job := &AsyncJob{
+		JobID:   jobResult.JobID,
+		Command: request.APIName(),
+		Created: time.Now(),
+		client:  exo,
+	}
+
+	// The result was given right away, a bare one without any status being successful
+	if jobResult.JobID == "" || jobResult.JobStatus != Pending {
+		if jobResult.JobID == "" && jobResult.JobStatus == Pending {
+			jobResult.JobStatus = Success
+		}
+		job.result = jobResult
+	}
+
+	return job, nil
+}
+
+// Status returns the last known status of the job
+func (job *AsyncJob) Status() JobStatusType {
+	if job.result == nil {
+		return Pending
+	}
+	return job.result.JobStatus
+}
+
+// Poll queries the current status of the job, once
+func (job *AsyncJob) Poll(ctx context.Context) (*AsyncJobResult, error) {
+	if job.result != nil && job.result.JobStatus != Pending {
+		return job.result, nil
+	}
+
+	job.polls++
+	resp, err := job.client.syncRequest(ctx, &QueryAsyncJobResult{JobID: job.JobID})
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := resp.(*QueryAsyncJobResultResponse)
+	if !ok {
+		return nil, &UnexpectedResponseError{Command: "queryAsyncJobResult", Response: resp}
+	}
+
+	job.result = (*AsyncJobResult)(result)
+	if job.Created.IsZero() && result.Created != nil {
+		job.Created = result.Created.Time
+	}
+
+	return job.result, nil
+}
+
+// Wait polls the job until it completes, fails or the context is done
+//
+// The polling follows the RetryStrategies (or RetryStrategy), PollMaxInterval,
+// PollMaxWait and PollProgress of the client. If the waiting is interrupted,
+// an AsyncJobError is returned, otherwise a failed job returns its ErrorResponse.
+func (job *AsyncJob) Wait(ctx context.Context) (err error) {
+	exo := job.client
+
+	start, polls := time.Now(), job.polls
+	ctx, span := exo.startSpan(ctx, "egoscale.Wait", job.Command)
+	span.SetAttribute("jobid", job.JobID)
+	defer func() {
+		span.SetAttribute("jobstatus", job.Status())
+		endSpan(span, err)
+
+		if exo.Metrics != nil {
+			exo.Metrics.ObserveJob(job.Command, time.Since(start), job.polls-polls, err)
+		}
+	}()
+
+	waitCtx := ctx
+	if exo.PollMaxWait > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, exo.PollMaxWait)
+		defer cancel()
+	}
+
+	strategy := exo.retryStrategy(job.Command)
+	for iteration := 0; job.Status() == Pending; iteration++ {
+		delay := strategy(int64(iteration))
+		if exo.PollMaxInterval > 0 && delay > exo.PollMaxInterval {
+			delay = exo.PollMaxInterval
+		}
+
+		if err := sleep(waitCtx, delay); err != nil {
+			return &AsyncJobError{Command: job.Command, JobID: job.JobID, Err: err}
+		}
+
+		result, err := job.Poll(waitCtx)
+		if err != nil {
+			return &AsyncJobError{Command: job.Command, JobID: job.JobID, Err: err}
+		}
+
+		if exo.PollProgress != nil {
+			exo.PollProgress(result, iteration+1)
+		}
+	}
+
+	if job.Status() == Failure {
+		r := new(ErrorResponse)
+		if job.result.JobResult == nil {
+			return fmt.Errorf("The job %s has failed", job.JobID)
+		}
+		if e := json.Unmarshal(*job.result.JobResult, r); e != nil {
+			return e
+		}
+		return r
+	}
+
+	return nil
+}
+
+// Result unmarshals the result of a successfully completed job into the given response
+func (job *AsyncJob) Result(response interface{}) error {
+	switch job.Status() {
+	case Pending:
+		return fmt.Errorf("The job %s is still pending", job.JobID)
+	case Failure:
+		return fmt.Errorf("The job %s has failed", job.JobID)
+	}
+
+	if job.result.JobResult == nil {
+		return nil
+	}
+	return json.Unmarshal(*(job.result.JobResult), response)
+}
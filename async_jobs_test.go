@@ -1,7 +1,9 @@
 package egoscale
 
 import (
+	"context"
 	"testing"
+	"time"
 )
 
 func TestAsyncJobs(t *testing.T) {
@@ -24,3 +26,238 @@ func TestListAsyncJobs(t *testing.T) {
 	}
 	_ = req.response().(*ListAsyncJobsResponse)
 }
+
+const pendingJobResponse = `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobprocstatus": 1,
+	"jobstatus": 0
+}}`
+
+func TestAsyncRequestProgress(t *testing.T) {
+	ts := newServer(response{200, `
+{"expungevirtualmachine": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, pendingJobResponse}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobprocstatus": 0,
+	"jobresult": {
+		"success": true
+	},
+	"jobstatus": 1
+}}`})
+	defer ts.Close()
+
+	statuses := make([]JobStatusType, 0)
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = func(int64) time.Duration { return time.Hour }
+	cs.PollMaxInterval = time.Millisecond
+	cs.PollProgress = func(job *AsyncJobResult, poll int) {
+		if job.JobID != "1" {
+			t.Errorf("bad job id, got %q", job.JobID)
+		}
+		if poll != len(statuses)+1 {
+			t.Errorf("bad poll count, got %d", poll)
+		}
+		statuses = append(statuses, job.JobStatus)
+	}
+
+	if err := cs.BooleanRequest(&ExpungeVirtualMachine{ID: "123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || statuses[0] != Pending || statuses[1] != Success {
+		t.Errorf("a pending then a successful status were expected, got %v", statuses)
+	}
+}
+
+func TestAsyncRequestCancel(t *testing.T) {
+	ts := newServer(response{200, `
+{"expungevirtualmachine": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = func(int64) time.Duration { return time.Hour }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := cs.RequestWithContext(ctx, &ExpungeVirtualMachine{ID: "123"})
+	if time.Since(start) > time.Second {
+		t.Errorf("the cancellation should have interrupted the wait")
+	}
+
+	e, ok := err.(*AsyncJobError)
+	if !ok {
+		t.Fatalf("an AsyncJobError was expected, got %v", err)
+	}
+	if e.JobID != "1" || e.Command != "expungeVirtualMachine" || e.Err != context.Canceled {
+		t.Errorf("bad AsyncJobError, got %#v", e)
+	}
+}
+
+func TestAsyncRequestMaxWait(t *testing.T) {
+	responses := []response{{200, `
+{"expungevirtualmachine": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}}
+	for i := 0; i < 100; i++ {
+		responses = append(responses, response{200, pendingJobResponse})
+	}
+	ts := newServer(responses...)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = func(int64) time.Duration { return 5 * time.Millisecond }
+	cs.PollMaxWait = 50 * time.Millisecond
+
+	_, err := cs.RequestWithContext(context.Background(), &ExpungeVirtualMachine{ID: "123"})
+	e, ok := err.(*AsyncJobError)
+	if !ok {
+		t.Fatalf("an AsyncJobError was expected, got %v", err)
+	}
+	if e.JobID != "1" {
+		t.Errorf("bad job id, got %q", e.JobID)
+	}
+}
+
+func TestSubmitAndWait(t *testing.T) {
+	ts := newServer(response{200, `
+{"deployvirtualmachineresponse": {
+	"jobid": "42",
+	"jobstatus": 0
+}}`}, response{200, pendingJobResponse}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "42",
+	"jobresult": {
+		"virtualmachine": {
+			"id": "123",
+			"name": "foo"
+		}
+	},
+	"jobstatus": 1
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = func(int64) time.Duration { return time.Millisecond }
+
+	job, err := cs.Submit(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.JobID != "42" || job.Command != "deployVirtualMachine" || job.Created.IsZero() {
+		t.Errorf("bad job handle, got %#v", job)
+	}
+
+	resp := new(DeployVirtualMachineResponse)
+	if err := job.Result(resp); err == nil {
+		t.Errorf("a pending job has no result")
+	}
+
+	result, err := job.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.JobStatus != Pending {
+		t.Errorf("a pending job was expected, got %v", result.JobStatus)
+	}
+
+	if err := job.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := job.Result(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.VirtualMachine.ID != "123" {
+		t.Errorf("bad virtual machine, got %#v", resp.VirtualMachine)
+	}
+}
+
+func TestResumeAsyncJob(t *testing.T) {
+	ts := newServer(response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "42",
+	"created": "2018-04-03T22:40:04+0200",
+	"jobresult": {
+		"errorcode": 531,
+		"cserrorcode": 4250,
+		"errortext": "Not enough permissions"
+	},
+	"jobstatus": 2
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = func(int64) time.Duration { return 0 }
+
+	job := cs.ResumeAsyncJob("42")
+	err := job.Wait(context.Background())
+	r, ok := err.(*ErrorResponse)
+	if !ok || r.ErrorCode != AccountError {
+		t.Errorf("the job error was expected, got %v", err)
+	}
+
+	if job.Status() != Failure {
+		t.Errorf("a failed job was expected, got %v", job.Status())
+	}
+
+	if job.Created.IsZero() {
+		t.Errorf("the creation date of the job should have been set")
+	}
+}
+
+func TestSubmitSyncCommand(t *testing.T) {
+	cs := NewClient("http://127.0.0.1", "KEY", "SECRET")
+	if _, err := cs.Submit(&ListZones{}); err == nil {
+		t.Errorf("a sync command cannot be submitted")
+	}
+}
+
+func TestSubmitImmediateFailure(t *testing.T) {
+	ts := newServer(response{200, `
+{"deployvirtualmachineresponse": {
+	"jobid": "42",
+	"jobstatus": 2,
+	"jobresult": {
+		"errorcode": 531,
+		"cserrorcode": 4250,
+		"errortext": "Not enough permissions"
+	}
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	job, err := cs.Submit(&DeployVirtualMachine{ServiceOfferingID: "1", TemplateID: "2", ZoneID: "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status() != Failure {
+		t.Errorf("a failed job was expected, got %v", job.Status())
+	}
+
+	err = job.Wait(context.Background())
+	r, ok := err.(*ErrorResponse)
+	if !ok || r.ErrorCode != AccountError {
+		t.Errorf("the job error was expected, got %v", err)
+	}
+
+	if err := job.Result(new(DeployVirtualMachineResponse)); err == nil {
+		t.Errorf("the result of a failed job should be an error")
+	}
+}
@@ -0,0 +1,176 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// BatchOptions represents how the commands of a Batch are run
+type BatchOptions struct {
+	// Concurrency is the number of commands running at once, one if not set
+	Concurrency int
+	// RateLimiter limits the rate at which the commands are started, if set
+	RateLimiter *RateLimiter
+	// StopOnError prevents any new command from starting once one has failed
+	StopOnError bool
+}
+
+// CommandError represents a failed command of a Batch
+type CommandError struct {
+	// Index is the position of the command in the batch
+	Index   int
+	Command Command
+	Err     error
+}
+
+// Error formats the error
+func (e *CommandError) Error() string {
+	name := "<nil>"
+	if e.Command != nil {
+		name = e.Command.APIName()
+	}
+	return fmt.Sprintf("The command #%d (%s) has failed: %s", e.Index, name, e.Err)
+}
+
+// Unwrap returns the error of the command
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError represents the failures of a Batch
+//
+// errors.Is and errors.As look into every failed command, e.g.
+// errors.Is(err, egoscale.ErrNotFound) tells whether any of them wasn't found.
+type BatchError struct {
+	// Errors holds the failed commands, in order
+	Errors []*CommandError
+	// Skipped holds the positions of the commands not run because of StopOnError
+	Skipped []int
+}
+
+// Error formats the error
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	msg := fmt.Sprintf("%d command(s) of the batch have failed", len(e.Errors))
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf(", %d skipped", len(e.Skipped))
+	}
+	return msg + ": " + strings.Join(messages, "; ")
+}
+
+// Is tells whether any of the failed commands matches the target
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failed command matching the target
+func (e *BatchError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch runs the commands, using RequestWithContext, and returns their responses in the same order
+//
+// The failed commands are reported through a BatchError, their response
+// being nil. Once the context is done, the commands not yet started fail
+// with its error.
+//
+//	cmds := make([]egoscale.Command, len(vms))
+//	for i, vm := range vms {
+//		cmds[i] = &egoscale.StopVirtualMachine{ID: vm.ID}
+//	}
+//
+//	resps, err := cs.Batch(ctx, cmds, egoscale.BatchOptions{Concurrency: 10})
+func (exo *Client) Batch(ctx context.Context, cmds []Command, opts BatchOptions) ([]interface{}, error) {
+	results := make([]interface{}, len(cmds))
+	errs := make([]error, len(cmds))
+	skipped := make([]int, 0)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(cmds) {
+		concurrency = len(cmds)
+	}
+
+	indexes := make(chan int)
+	stop := make(chan struct{})
+	var once sync.Once
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if opts.RateLimiter != nil {
+					errs[i] = opts.RateLimiter.Wait(ctx)
+				}
+				if errs[i] == nil {
+					results[i], errs[i] = exo.RequestWithContext(ctx, cmds[i])
+				}
+				if errs[i] != nil && opts.StopOnError {
+					once.Do(func() { close(stop) })
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range cmds {
+		select {
+		case <-stop:
+			for j := i; j < len(cmds); j++ {
+				skipped = append(skipped, j)
+			}
+			break feed
+		default:
+		}
+
+		select {
+		case indexes <- i:
+		case <-stop:
+			for j := i; j < len(cmds); j++ {
+				skipped = append(skipped, j)
+			}
+			break feed
+		case <-ctx.Done():
+			for j := i; j < len(cmds); j++ {
+				errs[j] = ctx.Err()
+			}
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	batchErr := &BatchError{Skipped: skipped}
+	for i, err := range errs {
+		if err != nil {
+			results[i] = nil
+			batchErr.Errors = append(batchErr.Errors, &CommandError{Index: i, Command: cmds[i], Err: err})
+		}
+	}
+
+	if len(batchErr.Errors) == 0 && len(batchErr.Skipped) == 0 {
+		return results, nil
+	}
+	return results, batchErr
+}
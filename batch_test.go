@@ -0,0 +1,209 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newBatchServer(running, max *int, mu *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		name := r.PostForm.Get("name")
+
+		mu.Lock()
+		*running++
+		if *running > *max {
+			*max = *running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		*running--
+		mu.Unlock()
+
+		if name == "bad" {
+			w.WriteHeader(431)
+			w.Write([]byte(`{"listzonesresponse": {"errorcode": 431, "errortext": "Unable to execute API command listzones due to invalid value. Invalid parameter name value=bad due to incorrect long value format, or entity does not exist or due to incorrect parameter annotation for the field in api cmd class."}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"listzonesresponse": {"count": 1, "zone": [{"id": "1", "name": %q}]}}`, name)
+	}))
+}
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	running, max := 0, 0
+	ts := newBatchServer(&running, &max, &mu)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	cmds := make([]Command, 10)
+	for i := range cmds {
+		cmds[i] = &ListZones{Name: fmt.Sprintf("zone-%d", i)}
+	}
+	cmds[3] = &ListZones{Name: "bad"}
+	cmds[7] = &ListZones{Name: "bad"}
+
+	resps, err := cs.Batch(context.Background(), cmds, BatchOptions{Concurrency: 3})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("a BatchError was expected, got %v", err)
+	}
+	if len(batchErr.Errors) != 2 || batchErr.Errors[0].Index != 3 || batchErr.Errors[1].Index != 7 {
+		t.Errorf("the commands #3 and #7 should have failed, got %v", err)
+	}
+	if len(batchErr.Skipped) != 0 {
+		t.Errorf("no commands should have been skipped, got %v", batchErr.Skipped)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("the error should match ErrNotFound, got %v", err)
+	}
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.ErrorCode != ParamError {
+		t.Errorf("an ErrorResponse was expected, got %v", err)
+	}
+
+	for i, resp := range resps {
+		if i == 3 || i == 7 {
+			if resp != nil {
+				t.Errorf("#%d: no response was expected, got %#v", i, resp)
+			}
+			continue
+		}
+
+		zones, ok := resp.(*ListZonesResponse)
+		if !ok || len(zones.Zone) != 1 || zones.Zone[0].Name != fmt.Sprintf("zone-%d", i) {
+			t.Errorf("#%d: bad response, got %#v", i, resp)
+		}
+	}
+
+	if max > 3 {
+		t.Errorf("no more than 3 commands should run at once, got %d", max)
+	}
+}
+
+func TestBatchStopOnError(t *testing.T) {
+	var mu sync.Mutex
+	running, max := 0, 0
+	ts := newBatchServer(&running, &max, &mu)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	cmds := []Command{
+		&ListZones{Name: "zone-0"},
+		&ListZones{Name: "bad"},
+		&ListZones{Name: "zone-2"},
+		&ListZones{Name: "zone-3"},
+	}
+
+	resps, err := cs.Batch(context.Background(), cmds, BatchOptions{StopOnError: true})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("a BatchError was expected, got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Index != 1 {
+		t.Errorf("the command #1 should have failed, got %v", err)
+	}
+	if len(batchErr.Skipped) != 2 || batchErr.Skipped[0] != 2 || batchErr.Skipped[1] != 3 {
+		t.Errorf("the commands #2 and #3 should have been skipped, got %v", batchErr.Skipped)
+	}
+	if resps[0] == nil || resps[2] != nil || resps[3] != nil {
+		t.Errorf("only the first command should have a response, got %#v", resps)
+	}
+}
+
+func TestBatchRateLimiter(t *testing.T) {
+	var mu sync.Mutex
+	running, max := 0, 0
+	ts := newBatchServer(&running, &max, &mu)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	cmds := make([]Command, 4)
+	for i := range cmds {
+		cmds[i] = &ListZones{Name: fmt.Sprintf("zone-%d", i)}
+	}
+
+	rl, err := NewRateLimiter(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = cs.Batch(ctx, cmds, BatchOptions{
+		Concurrency: 4,
+		RateLimiter: rl,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the rate limiter should have been waited upon until the deadline, got %v", err)
+	}
+}
+
+func TestBatchRateLimiterStopOnError(t *testing.T) {
+	var mu sync.Mutex
+	running, max := 0, 0
+	ts := newBatchServer(&running, &max, &mu)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	cmds := make([]Command, 4)
+	for i := range cmds {
+		cmds[i] = &ListZones{Name: fmt.Sprintf("zone-%d", i)}
+	}
+
+	rl, err := NewRateLimiter(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resps, err := cs.Batch(ctx, cmds, BatchOptions{
+		RateLimiter: rl,
+		StopOnError: true,
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("a BatchError was expected, got %v", err)
+	}
+	if len(batchErr.Errors) == 0 || batchErr.Errors[0].Index != 1 || !errors.Is(batchErr.Errors[0], context.DeadlineExceeded) {
+		t.Errorf("the command #1 should have failed waiting for the rate limiter, got %v", err)
+	}
+	if len(batchErr.Errors)+len(batchErr.Skipped) != 3 {
+		t.Errorf("the commands after #0 should have failed or been skipped, got %v", err)
+	}
+	if resps[0] == nil || resps[1] != nil || resps[2] != nil || resps[3] != nil {
+		t.Errorf("only the first command should have a response, got %#v", resps)
+	}
+}
+
+func TestBatchEmpty(t *testing.T) {
+	cs := NewClient("http://localhost", "KEY", "SECRET")
+
+	resps, err := cs.Batch(context.Background(), nil, BatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 0 {
+		t.Errorf("no responses were expected, got %#v", resps)
+	}
+}
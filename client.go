@@ -133,11 +133,16 @@ func (client *Client) PaginateWithContext(ctx context.Context, req ListCommand,
 
 	page := 1
 
+	ctx, span := client.startSpan(ctx, "egoscale.Paginate", req.APIName())
+	defer span.End()
+
 	for {
 		req.SetPage(page)
 		req.SetPageSize(pageSize)
+		span.SetAttribute("page", page)
 		resp, err := client.RequestWithContext(ctx, req)
 		if err != nil {
+			span.RecordError(err)
 			callback(nil, err)
 			break
 		}
@@ -183,13 +188,14 @@ func NewClientWithTimeout(endpoint, apiKey, apiSecret string, timeout time.Durat
 	}
 
 	cs := &Client{
-		client:        client,
-		endpoint:      endpoint,
-		apiKey:        apiKey,
-		apiSecret:     apiSecret,
-		PageSize:      50,
-		Timeout:       timeout,
-		RetryStrategy: FibonacciRetryStrategy,
+		endpoint:            endpoint,
+		credentialsProvider: NewStaticCredentials(apiKey, apiSecret),
+		HTTPClient:          client,
+		PageSize:            50,
+		Timeout:             timeout,
+		RetryStrategy:       FibonacciRetryStrategy,
+		ThrottleRetries:     3,
+		Logger:              DefaultLogger,
 	}
 
 	return cs
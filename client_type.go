@@ -3,6 +3,7 @@ package egoscale
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -26,20 +27,55 @@ type Listable interface {
 
 // Client represents the CloudStack API client
 type Client struct {
-	client    *http.Client
-	endpoint  string
-	apiKey    string
-	apiSecret string
+	endpoint            string
+	dnsEndpoint         string
+	credentialsMu       sync.RWMutex
+	credentialsProvider CredentialsProvider
+	// HTTPClient holds the HTTP client, its Transport may be replaced, e.g. for testing
+	HTTPClient *http.Client
 	// PageSize represents the default size for a paginated result
 	PageSize int
 	// Timeout represents the default timeout for the async requests
 	Timeout time.Duration
 	// RetryStrategy represents the waiting strategy for polling the async requests
 	RetryStrategy RetryStrategyFunc
+	// RetryStrategies overrides the RetryStrategy for the given async commands, e.g. "deployVirtualMachine"
+	RetryStrategies map[string]RetryStrategyFunc
+	// PollMaxInterval caps the time waited between two polls of an async job, if set
+	PollMaxInterval time.Duration
+	// PollMaxWait represents the maximum time spent waiting for an async job, if set
+	PollMaxWait time.Duration
+	// PollProgress is called after every poll of an async job
+	PollProgress JobProgressFunc
+	// Interceptors represents the hooks called around every request, in order
+	Interceptors []Interceptor
+	// RateLimiter throttles the requests sent to the API, if set
+	RateLimiter *RateLimiter
+	// ThrottleRetries represents how many times a request is retried on APILimitExceeded
+	ThrottleRetries int
+	// OnThrottle is called every time a request is throttled by the API
+	OnThrottle ThrottleFunc
+	// RetryPolicy retries the idempotent commands on transient failures, if set
+	RetryPolicy *RetryPolicy
+	// Logger receives the structured logs of every API call, without the secrets
+	Logger Logger
+	// Metrics receives the measurements of every API call, if set
+	Metrics Metrics
+	// Tracer opens the spans around every API call, if set
+	Tracer Tracer
+	// DryRun logs the mutating commands, i.e. not list*, get* or query*, instead of sending them
+	DryRun bool
+	// Strict reports the unknown fields and the type mismatches of the responses, StrictOff by default
+	Strict StrictMode
+	// Expiration makes the signed requests expire after that long, using the version 3 of the signature, if set
+	Expiration time.Duration
 }
 
 // RetryStrategyFunc represents a how much time to wait between two calls to CloudStack
 type RetryStrategyFunc func(int64) time.Duration
 
+// JobProgressFunc represents the callback receiving the status of an async job after each poll
+type JobProgressFunc func(job *AsyncJobResult, poll int)
+
 // IterateItemFunc represents the callback to iterate a list of results, if false stops
 type IterateItemFunc func(interface{}, error) bool
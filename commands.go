@@ -0,0 +1,144 @@
+package egoscale
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// commands is the registry of the known commands, by lowercase API name
+var commands = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{types: make(map[string]reflect.Type)}
+
+func init() {
+	for _, cmd := range []Command{
+		&AddIPToNic{},
+		&AddNicToVirtualMachine{},
+		&AssociateIPAddress{},
+		&AuthorizeSecurityGroupEgress{},
+		&AuthorizeSecurityGroupIngress{},
+		&ChangeServiceForVirtualMachine{},
+		&CreateAffinityGroup{},
+		&CreateInstanceGroup{},
+		&CreateNetwork{},
+		&CreateSSHKeyPair{},
+		&CreateSecurityGroup{},
+		&CreateSnapshot{},
+		&CreateTags{},
+		&DeleteAffinityGroup{},
+		&DeleteInstanceGroup{},
+		&DeleteNetwork{},
+		&DeleteSSHKeyPair{},
+		&DeleteSecurityGroup{},
+		&DeleteSnapshot{},
+		&DeleteTags{},
+		&DeployVirtualMachine{},
+		&DestroyVirtualMachine{},
+		&DisassociateIPAddress{},
+		&ExpungeVirtualMachine{},
+		&GetVMPassword{},
+		&ListAPIs{},
+		&ListAccounts{},
+		&ListAffinityGroupTypes{},
+		&ListAffinityGroups{},
+		&ListAsyncJobs{},
+		&ListEventTypes{},
+		&ListEvents{},
+		&ListInstanceGroups{},
+		&ListNetworkOfferings{},
+		&ListNetworks{},
+		&ListNics{},
+		&ListPublicIPAddresses{},
+		&ListResourceLimits{},
+		&ListSSHKeyPairs{},
+		&ListSecurityGroups{},
+		&ListServiceOfferings{},
+		&ListSnapshots{},
+		&ListTags{},
+		&ListTemplates{},
+		&ListVirtualMachines{},
+		&ListVolumes{},
+		&ListZones{},
+		&QueryAsyncJobResult{},
+		&RebootVirtualMachine{},
+		&RecoverVirtualMachine{},
+		&RegisterSSHKeyPair{},
+		&RegisterUserKeys{},
+		&RemoveIPFromNic{},
+		&RemoveNicFromVirtualMachine{},
+		&ResetPasswordForVirtualMachine{},
+		&ResetSSHKeyForVirtualMachine{},
+		&ResizeVolume{},
+		&RestartNetwork{},
+		&RestoreVirtualMachine{},
+		&RevertSnapshot{},
+		&RevokeSecurityGroupEgress{},
+		&RevokeSecurityGroupIngress{},
+		&ScaleVirtualMachine{},
+		&StartVirtualMachine{},
+		&StopVirtualMachine{},
+		&UpdateDefaultNicForVirtualMachine{},
+		&UpdateIPAddress{},
+		&UpdateInstanceGroup{},
+		&UpdateNetwork{},
+		&UpdateVMAffinityGroup{},
+		&UpdateVirtualMachine{},
+	} {
+		RegisterCommand(cmd)
+	}
+}
+
+// RegisterCommand makes the command known to DecodeCommand
+//
+// The command must be a pointer to a struct, it replaces any command
+// registered under the same API name.
+func RegisterCommand(cmd Command) {
+	t := reflect.TypeOf(cmd)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("egoscale: a command must be a pointer to a struct, got %T", cmd))
+	}
+
+	commands.Lock()
+	defer commands.Unlock()
+	commands.types[strings.ToLower(cmd.APIName())] = t.Elem()
+}
+
+// DecodeCommand builds the command out of the parameters of a CloudStack request
+//
+// It's the inverse of what the client does when it sends a command, the
+// command name is read from the "command" parameter, case insensitively.
+// The other parameters, e.g. apikey or signature, are ignored.
+func DecodeCommand(values url.Values) (Command, error) {
+	name := values.Get("command")
+	if name == "" {
+		return nil, fmt.Errorf("The command parameter is missing")
+	}
+
+	commands.RLock()
+	t, ok := commands.types[strings.ToLower(name)]
+	commands.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("The command %q is unknown", name)
+	}
+
+	value := reflect.New(t)
+	if err := decodeValues("", values, value.Elem()); err != nil {
+		return nil, err
+	}
+
+	cmd := value.Interface().(Command)
+
+	// the required fields are checked the same way as when sending the command
+	if err := prepareValues("", &url.Values{}, cmd, nil); err != nil {
+		return nil, err
+	}
+	if err := Validate(cmd); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,106 @@
+package egoscale
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCommand(t *testing.T) {
+	tr := true
+	commands := []Command{
+		&DeployVirtualMachine{
+			ServiceOfferingID:  "1",
+			TemplateID:         "2",
+			ZoneID:             "3",
+			Name:               "test",
+			RootDiskSize:       50,
+			IP4:                &tr,
+			IP6Address:         net.ParseIP("2001:db8::1"),
+			SecurityGroupNames: []string{"default", "web"},
+			UserData:           "I2Nsb3VkLWNvbmZpZwo=",
+			Details:            map[string]string{"foo": "bar"},
+		},
+		&CreateTags{
+			ResourceIDs:  []string{"1", "2"},
+			ResourceType: "UserVm",
+			Tags: []ResourceTag{
+				{Key: "env", Value: "prod"},
+				{Key: "app", Value: "web"},
+			},
+		},
+		&AuthorizeSecurityGroupIngress{
+			SecurityGroupName: "web",
+			Protocol:          "tcp",
+			StartPort:         80,
+			EndPort:           80,
+			CidrList:          []string{"0.0.0.0/0"},
+			UserSecurityGroupList: []UserSecurityGroup{
+				{Group: "default", Account: "test"},
+			},
+		},
+		&ListVirtualMachines{},
+	}
+
+	for _, cmd := range commands {
+		params := url.Values{}
+		if err := prepareValues("", &params, cmd, nil); err != nil {
+			t.Fatal(err)
+		}
+		params.Set("command", cmd.APIName())
+		params.Set("apikey", "KEY")
+		params.Set("signature", "SIGNATURE")
+
+		decoded, err := DecodeCommand(params)
+		if err != nil {
+			t.Fatalf("%s: %s", cmd.APIName(), err)
+		}
+		if !reflect.DeepEqual(cmd, decoded) {
+			t.Errorf("%s: bad command, got %#v", cmd.APIName(), decoded)
+		}
+	}
+}
+
+func TestDecodeCommandCaseInsensitive(t *testing.T) {
+	cmd, err := DecodeCommand(url.Values{"command": {"listzones"}, "name": {"ch-gva-2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zones, ok := cmd.(*ListZones); !ok || zones.Name != "ch-gva-2" {
+		t.Errorf("bad command, got %#v", cmd)
+	}
+}
+
+func TestDecodeCommandFailure(t *testing.T) {
+	tests := []url.Values{
+		{},
+		{"command": {"doSomething"}},
+		{"command": {"deployVirtualMachine"}, "templateid": {"1"}},
+		{"command": {"resizeVolume"}, "id": {"1"}, "size": {"big"}},
+	}
+
+	for _, params := range tests {
+		if _, err := DecodeCommand(params); err == nil {
+			t.Errorf("an error was expected for %v", params)
+		}
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	RegisterCommand(&dummyCommand{})
+	defer func() {
+		commands.Lock()
+		delete(commands.types, strings.ToLower((&dummyCommand{}).APIName()))
+		commands.Unlock()
+	}()
+
+	cmd, err := DecodeCommand(url.Values{"command": {(&dummyCommand{}).APIName()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cmd.(*dummyCommand); !ok {
+		t.Errorf("a dummyCommand was expected, got %#v", cmd)
+	}
+}
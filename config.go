@@ -0,0 +1,401 @@
+package egoscale
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the Exoscale compute API endpoint
+const DefaultEndpoint = "https://api.exoscale.ch/compute"
+
+// DefaultDNSEndpoint is the Exoscale DNS API endpoint
+const DefaultDNSEndpoint = "https://api.exoscale.ch/dns"
+
+// DefaultTimeout is the timeout used when none is configured
+const DefaultTimeout = 60 * time.Second
+
+// profile represents the settings of one account
+type profile struct {
+	name        string
+	endpoint    string
+	dnsEndpoint string
+	key         string
+	secret      string
+	timeout     time.Duration
+	pageSize    int
+}
+
+// set assigns the given setting, the key is normalized beforehand
+func (p *profile) set(key, value string) error {
+	switch normalizeKey(key) {
+	case "name":
+		p.name = value
+	case "endpoint", "computeendpoint":
+		p.endpoint = value
+	case "dnsendpoint":
+		p.dnsEndpoint = value
+	case "key", "apikey":
+		p.key = value
+	case "secret", "secretkey", "apisecret":
+		p.secret = value
+	case "timeout":
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return err
+		}
+		p.timeout = timeout
+	case "pagesize":
+		pageSize, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("Invalid page size %q", value)
+		}
+		p.pageSize = pageSize
+	}
+	return nil
+}
+
+// credentials returns the API key and secret of the profile
+func (p *profile) credentials() (Credentials, error) {
+	if p.key == "" || p.secret == "" {
+		return Credentials{}, fmt.Errorf("The API key and secret of the profile %q are missing", p.name)
+	}
+
+	return Credentials{APIKey: p.key, APISecret: p.secret}, nil
+}
+
+// client builds the client out of the profile
+func (p *profile) client() (*Client, error) {
+	if _, err := p.credentials(); err != nil {
+		return nil, err
+	}
+
+	endpoint := p.endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	timeout := p.timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	dnsEndpoint := p.dnsEndpoint
+	if dnsEndpoint == "" {
+		dnsEndpoint = DefaultDNSEndpoint
+	}
+
+	cs := NewClientWithTimeout(endpoint, p.key, p.secret, timeout)
+	cs.dnsEndpoint = dnsEndpoint
+	if p.pageSize > 0 {
+		cs.PageSize = p.pageSize
+	}
+
+	return cs, nil
+}
+
+// NewClientFromEnv creates a client configured by the environment variables
+//
+// The EXOSCALE_* variables have precedence over the CLOUDSTACK_* ones.
+//
+//	EXOSCALE_ENDPOINT       CLOUDSTACK_ENDPOINT
+//	EXOSCALE_DNS_ENDPOINT
+//	EXOSCALE_KEY            CLOUDSTACK_KEY
+//	EXOSCALE_SECRET         CLOUDSTACK_SECRET
+//	EXOSCALE_TIMEOUT        CLOUDSTACK_TIMEOUT
+//	EXOSCALE_PAGE_SIZE      CLOUDSTACK_PAGE_SIZE
+//
+// EXOSCALE_API_KEY and EXOSCALE_API_SECRET (or CLOUDSTACK_API_KEY and
+// CLOUDSTACK_SECRET_KEY) are accepted as well. The timeout is either a
+// number of seconds or a duration, e.g. "1m30s".
+func NewClientFromEnv() (*Client, error) {
+	p, err := envProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.client()
+}
+
+// envSettings lists the environment variables of each setting, by order of precedence
+var envSettings = []struct {
+	key  string
+	vars []string
+}{
+	{"endpoint", []string{"EXOSCALE_ENDPOINT", "EXOSCALE_COMPUTE_ENDPOINT", "CLOUDSTACK_ENDPOINT"}},
+	{"dnsendpoint", []string{"EXOSCALE_DNS_ENDPOINT"}},
+	{"key", []string{"EXOSCALE_KEY", "EXOSCALE_API_KEY", "CLOUDSTACK_KEY", "CLOUDSTACK_API_KEY"}},
+	{"secret", []string{"EXOSCALE_SECRET", "EXOSCALE_API_SECRET", "EXOSCALE_SECRET_KEY", "CLOUDSTACK_SECRET", "CLOUDSTACK_SECRET_KEY"}},
+	{"timeout", []string{"EXOSCALE_TIMEOUT", "CLOUDSTACK_TIMEOUT"}},
+	{"pagesize", []string{"EXOSCALE_PAGE_SIZE", "CLOUDSTACK_PAGE_SIZE"}},
+}
+
+// envProfile reads the profile defined by the environment variables
+func envProfile() (*profile, error) {
+	p := &profile{name: "env"}
+	for _, setting := range envSettings {
+		for _, name := range setting.vars {
+			if value := os.Getenv(name); value != "" {
+				if err := p.set(setting.key, value); err != nil {
+					return nil, fmt.Errorf("%s: %s", name, err)
+				}
+				break
+			}
+		}
+	}
+	return p, nil
+}
+
+// NewClientFromConfig creates a client using the given profile of a configuration file
+//
+// Both the cloudstack.ini and the exoscale.toml formats are supported.
+//
+//	; cloudstack.ini
+//	[cloudstack]
+//	endpoint = https://api.exoscale.ch/compute
+//	key = EXO...
+//	secret = ...
+//	timeout = 60
+//
+//	# exoscale.toml
+//	defaultaccount = "production"
+//
+//	[[accounts]]
+//	name = "production"
+//	endpoint = "https://api.exoscale.ch/compute"
+//	dnsEndpoint = "https://api.exoscale.ch/dns"
+//	key = "EXO..."
+//	secret = "..."
+//
+// An empty profile selects the default one: the "cloudstack" section of an
+// INI file or the defaultaccount of a TOML file. Without a DNS endpoint, the
+// DefaultDNSEndpoint is used.
+func NewClientFromConfig(path, profileName string) (*Client, error) {
+	p, err := loadProfile(path, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.client()
+}
+
+// loadProfile reads the given profile of a configuration file
+func loadProfile(path, profileName string) (*profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profiles []*profile
+	var defaultName string
+	if isTOML(path) {
+		profiles, defaultName, err = readTOMLProfiles(f)
+	} else {
+		profiles, err = readINIProfiles(f)
+		defaultName = "cloudstack"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if profileName == "" {
+		profileName = defaultName
+	}
+
+	for _, p := range profiles {
+		if p.name == profileName || (profileName == "" && len(profiles) == 1) {
+			return p, nil
+		}
+	}
+
+	if profileName == "" {
+		return nil, fmt.Errorf("No default profile was found in %s", path)
+	}
+	return nil, fmt.Errorf("The profile %q was not found in %s", profileName, path)
+}
+
+// isTOML tells whether the file is a TOML one, the INI format is the default
+func isTOML(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".toml"
+}
+
+// readINIProfiles reads the sections of an INI file, each one being a profile
+func readINIProfiles(r io.Reader) ([]*profile, error) {
+	profiles := make([]*profile, 0)
+	var current *profile
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: bad section %q", n, line)
+			}
+			current = &profile{name: strings.TrimSpace(line[1 : len(line)-1])}
+			profiles = append(profiles, current)
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: a key = value pair was expected, got %q", n, line)
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %q is outside of any section", n, line)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value := unquote(strings.TrimSpace(line[i+1:]))
+		if normalizeKey(key) == "name" {
+			continue
+		}
+		if err := current.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+	}
+
+	return profiles, scanner.Err()
+}
+
+// readTOMLProfiles reads the accounts of a TOML file
+//
+// Only the subset of TOML used by the configuration files is supported: the
+// key = value pairs, the [[accounts]] array and the [accounts.name] tables.
+func readTOMLProfiles(r io.Reader) ([]*profile, string, error) {
+	profiles := make([]*profile, 0)
+	var defaultName string
+	var current *profile
+	root := true
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, "", fmt.Errorf("line %d: bad table %q", n, line)
+			}
+			table := strings.Trim(line, "[] \t")
+			current = nil
+			root = false
+			switch {
+			case strings.HasPrefix(line, "[[") && table == "accounts":
+				current = new(profile)
+				profiles = append(profiles, current)
+			case strings.HasPrefix(table, "accounts."):
+				current = &profile{name: unquote(strings.TrimPrefix(table, "accounts."))}
+				profiles = append(profiles, current)
+			}
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, "", fmt.Errorf("line %d: a key = value pair was expected, got %q", n, line)
+		}
+
+		key := unquote(strings.TrimSpace(line[:i]))
+		value, err := parseTOMLValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, "", fmt.Errorf("line %d: %s", n, err)
+		}
+
+		if current == nil {
+			if root && normalizeKey(key) == "defaultaccount" {
+				defaultName = value
+			}
+			continue
+		}
+
+		if err := current.set(key, value); err != nil {
+			return nil, "", fmt.Errorf("line %d: %s", n, err)
+		}
+	}
+
+	return profiles, defaultName, scanner.Err()
+}
+
+// parseTOMLValue parses a string, a number or a boolean into its string representation
+func parseTOMLValue(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("a value was expected")
+	}
+
+	switch value[0] {
+	case '"':
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("bad string %s", value)
+		}
+		return s, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("bad string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	case '[', '{':
+		return "", fmt.Errorf("arrays and inline tables are not supported, got %s", value)
+	}
+
+	return value, nil
+}
+
+// stripTOMLComment removes the trailing comment, if it's not within a string
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == 0 && c == '#':
+			return line[:i]
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		}
+	}
+	return line
+}
+
+// unquote removes the surrounding quotes, if any
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// normalizeKey makes dnsEndpoint, dns_endpoint and dns-endpoint the same key
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.Replace(key, "_", "", -1)
+	return strings.Replace(key, "-", "", -1)
+}
+
+// parseTimeout parses a number of seconds or a duration
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid timeout %q", value)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,220 @@
+package egoscale
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "egoscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func setenv(vars map[string]string) func() {
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func key(cs *Client) string {
+	creds, _ := cs.credentials(context.Background())
+	return creds.APIKey
+}
+
+func secret(cs *Client) string {
+	creds, _ := cs.credentials(context.Background())
+	return creds.APISecret
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	defer setenv(map[string]string{
+		"EXOSCALE_ENDPOINT":     "https://api.example.com/compute",
+		"EXOSCALE_DNS_ENDPOINT": "https://api.example.com/dns",
+		"EXOSCALE_KEY":          "EXO123",
+		"CLOUDSTACK_KEY":        "CS123",
+		"CLOUDSTACK_SECRET_KEY": "SECRET",
+		"EXOSCALE_TIMEOUT":      "1m30s",
+		"CLOUDSTACK_PAGE_SIZE":  "100",
+	})()
+
+	cs, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cs.endpoint != "https://api.example.com/compute" || cs.dnsEndpoint != "https://api.example.com/dns" {
+		t.Errorf("bad endpoints, got %q and %q", cs.endpoint, cs.dnsEndpoint)
+	}
+	if key(cs) != "EXO123" || secret(cs) != "SECRET" {
+		t.Errorf("bad credentials, got %q and %q", key(cs), secret(cs))
+	}
+	if cs.Timeout != 90*time.Second || cs.PageSize != 100 {
+		t.Errorf("bad timeout or page size, got %v and %d", cs.Timeout, cs.PageSize)
+	}
+}
+
+func TestNewClientFromEnvMissing(t *testing.T) {
+	defer setenv(map[string]string{
+		"EXOSCALE_KEY": "EXO123",
+	})()
+
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Errorf("an error was expected, the secret is missing")
+	}
+}
+
+const cloudstackINI = `
+; default one
+[cloudstack]
+endpoint = https://api.exoscale.ch/compute
+key = EXO123
+secret = SECRET
+timeout = 30
+
+[lab]
+endpoint: http://localhost:8080/client/api
+key = "LAB"
+secret = 'LABSECRET'
+page_size = 10
+`
+
+func TestNewClientFromConfigINI(t *testing.T) {
+	path, clean := writeConfig(t, "cloudstack.ini", cloudstackINI)
+	defer clean()
+
+	cs, err := NewClientFromConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key(cs) != "EXO123" || cs.Timeout != 30*time.Second || cs.PageSize != 50 {
+		t.Errorf("bad default profile, got %#v", cs)
+	}
+
+	cs, err = NewClientFromConfig(path, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.endpoint != "http://localhost:8080/client/api" || key(cs) != "LAB" || secret(cs) != "LABSECRET" || cs.PageSize != 10 {
+		t.Errorf("bad lab profile, got %#v", cs)
+	}
+
+	if _, err := NewClientFromConfig(path, "nope"); err == nil {
+		t.Errorf("an error was expected, the profile doesn't exist")
+	}
+}
+
+const exoscaleTOML = `
+defaultaccount = "production" # the one to use
+
+[[accounts]]
+name = "staging"
+endpoint = "https://api.example.com/compute"
+key = "EXOSTAGING"
+secret = "STAGING#SECRET"
+
+[[accounts]]
+name = "production"
+endpoint = "https://api.exoscale.ch/compute"
+dnsEndpoint = "https://api.exoscale.ch/dns"
+key = "EXOPRODUCTION"
+secret = "PRODUCTION"
+timeout = 120
+pageSize = 500
+defaultZone = "ch-gva-2"
+`
+
+func TestNewClientFromConfigINIDefault(t *testing.T) {
+	path, clean := writeConfig(t, "cloudstack.ini", `
+[lab]
+endpoint = http://localhost:8080/client/api
+key = LAB
+secret = LABSECRET
+
+[cloudstack]
+key = EXO123
+secret = SECRET
+`)
+	defer clean()
+
+	cs, err := NewClientFromConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key(cs) != "EXO123" || cs.endpoint != DefaultEndpoint || cs.dnsEndpoint != DefaultDNSEndpoint {
+		t.Errorf("the cloudstack section was expected, got %#v", cs)
+	}
+
+	path, clean = writeConfig(t, "cloudstack.ini", `
+[lab]
+key = LAB
+secret = LABSECRET
+`)
+	defer clean()
+
+	if _, err := NewClientFromConfig(path, ""); err == nil {
+		t.Errorf("an error was expected, there is no cloudstack section")
+	}
+}
+
+func TestNewClientFromConfigTOML(t *testing.T) {
+	path, clean := writeConfig(t, "exoscale.toml", exoscaleTOML)
+	defer clean()
+
+	cs, err := NewClientFromConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key(cs) != "EXOPRODUCTION" || cs.dnsEndpoint != DefaultDNSEndpoint || cs.Timeout != 2*time.Minute || cs.PageSize != 500 {
+		t.Errorf("bad default account, got %#v", cs)
+	}
+
+	cs, err = NewClientFromConfig(path, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key(cs) != "EXOSTAGING" || secret(cs) != "STAGING#SECRET" || cs.dnsEndpoint != DefaultDNSEndpoint {
+		t.Errorf("bad staging account, got %#v", cs)
+	}
+}
+
+func TestNewClientFromConfigBad(t *testing.T) {
+	path, clean := writeConfig(t, "exoscale.toml", "[[accounts]]\nname = [1, 2]\n")
+	defer clean()
+
+	if _, err := NewClientFromConfig(path, ""); err == nil {
+		t.Errorf("an error was expected, arrays are not supported")
+	}
+
+	if _, err := NewClientFromConfig(filepath.Join(filepath.Dir(path), "missing.ini"), ""); err == nil {
+		t.Errorf("an error was expected, the file doesn't exist")
+	}
+}
+
+func TestDNSEndpoint(t *testing.T) {
+	ts := newServer(response{200, `{"domain": {"id": 1, "name": "example.com"}}`})
+	defer ts.Close()
+
+	cs := NewClient("http://127.0.0.1:1", "KEY", "SECRET")
+	cs.dnsEndpoint = ts.URL
+	if _, err := cs.GetDomain("example.com"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+package egoscale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Credentials represents an API key and its secret
+type Credentials struct {
+	APIKey    string
+	APISecret string
+}
+
+// CredentialsProvider gives the credentials to use, it's called for every request
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials always gives the same credentials
+type StaticCredentials Credentials
+
+// NewStaticCredentials creates a provider of the given credentials
+func NewStaticCredentials(apiKey, apiSecret string) *StaticCredentials {
+	return &StaticCredentials{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+	}
+}
+
+// Credentials returns the credentials
+func (c *StaticCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials(*c), nil
+}
+
+// EnvCredentials reads the credentials from the environment variables, every time
+//
+// See NewClientFromEnv for the list of variables.
+type EnvCredentials struct{}
+
+// Credentials returns the credentials
+func (*EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	p, err := envProfile()
+	if err != nil {
+		return Credentials{}, err
+	}
+	return p.credentials()
+}
+
+// FileCredentials reads the credentials from a configuration file and reloads them when it changes
+//
+// See NewClientFromConfig for the supported formats.
+type FileCredentials struct {
+	path    string
+	profile string
+
+	mu          sync.Mutex
+	modTime     time.Time
+	credentials Credentials
+}
+
+// NewFileCredentials creates a provider reading the given profile of a configuration file
+func NewFileCredentials(path, profile string) *FileCredentials {
+	return &FileCredentials{
+		path:    path,
+		profile: profile,
+	}
+}
+
+// Credentials returns the credentials, the file is read again if it was modified
+func (c *FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.credentials.APIKey != "" && info.ModTime().Equal(c.modTime) {
+		return c.credentials, nil
+	}
+
+	p, err := loadProfile(c.path, c.profile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	creds, err := p.credentials()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	c.credentials = creds
+	c.modTime = info.ModTime()
+	return creds, nil
+}
+
+// CommandCredentials gets the credentials from an external command, e.g. a secret manager
+//
+// The command prints a JSON object on its standard output, using the same keys
+// as the configuration files.
+//
+//	{"key": "EXO...", "secret": "..."}
+//
+// The credentials are kept for TTL, if set, then the command is run again.
+type CommandCredentials struct {
+	Command string
+	Args    []string
+	TTL     time.Duration
+
+	mu          sync.Mutex
+	expires     time.Time
+	credentials Credentials
+}
+
+// NewCommandCredentials creates a provider running the given command
+func NewCommandCredentials(ttl time.Duration, command string, args ...string) *CommandCredentials {
+	return &CommandCredentials{
+		Command: command,
+		Args:    args,
+		TTL:     ttl,
+	}
+}
+
+// Credentials returns the credentials, the command is run once they expire
+func (c *CommandCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.credentials.APIKey != "" && (c.TTL == 0 || time.Now().Before(c.expires)) {
+		return c.credentials, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Credentials{}, fmt.Errorf("The command %s has failed: %s %s", c.Command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(stdout.Bytes(), &values); err != nil {
+		return Credentials{}, fmt.Errorf("The command %s didn't output a JSON object: %s", c.Command, err)
+	}
+
+	p := &profile{name: c.Command}
+	for k, v := range values {
+		if err := p.set(k, v); err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	creds, err := p.credentials()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	c.credentials = creds
+	c.expires = time.Now().Add(c.TTL)
+	return creds, nil
+}
+
+// credentials returns the credentials of the client
+func (exo *Client) credentials(ctx context.Context) (Credentials, error) {
+	exo.credentialsMu.RLock()
+	provider := exo.credentialsProvider
+	exo.credentialsMu.RUnlock()
+
+	if provider == nil {
+		return Credentials{}, fmt.Errorf("No credentials provider was set")
+	}
+	return provider.Credentials(ctx)
+}
+
+// CredentialsProvider returns the provider of the credentials
+func (exo *Client) CredentialsProvider() CredentialsProvider {
+	exo.credentialsMu.RLock()
+	defer exo.credentialsMu.RUnlock()
+
+	return exo.credentialsProvider
+}
+
+// SetCredentialsProvider replaces the provider of the credentials, it's safe to call while requests are running
+func (exo *Client) SetCredentialsProvider(provider CredentialsProvider) {
+	exo.credentialsMu.Lock()
+	defer exo.credentialsMu.Unlock()
+
+	exo.credentialsProvider = provider
+}
+
+// RotateKeys registers a new set of keys for the current user and swaps them into the client
+//
+// The current user is the one owning the API key in use. The previous keys
+// are revoked by CloudStack, the new ones are returned so they can be saved.
+//
+// Only the StaticCredentials can be swapped. With any other provider, e.g.
+// FileCredentials, an error is returned before anything is sent: the provider
+// would keep giving the revoked keys.
+func (exo *Client) RotateKeys(ctx context.Context) (*User, error) {
+	provider := exo.CredentialsProvider()
+	static, ok := provider.(*StaticCredentials)
+	if !ok {
+		return nil, fmt.Errorf("Only static credentials can be rotated, got %T", provider)
+	}
+
+	creds, err := static.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ListAccounts{}
+	resp, err := exo.RequestWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, ok := resp.(*ListAccountsResponse)
+	if !ok {
+		return nil, &UnexpectedResponseError{Command: req.APIName(), Response: resp}
+	}
+
+	var userID string
+	for _, account := range accounts.Account {
+		for _, user := range account.User {
+			if user.APIKey == creds.APIKey {
+				userID = user.ID
+			}
+		}
+	}
+	if userID == "" {
+		return nil, notFoundError("User not found. apikey: %s", creds.APIKey)
+	}
+
+	register := &RegisterUserKeys{ID: userID}
+	resp, err = exo.RequestWithContext(ctx, register)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, ok := resp.(*RegisterUserKeysResponse)
+	if !ok {
+		return nil, &UnexpectedResponseError{Command: register.APIName(), Response: resp}
+	}
+
+	exo.SetCredentialsProvider(NewStaticCredentials(keys.UserKeys.APIKey, keys.UserKeys.SecretKey))
+
+	return &keys.UserKeys, nil
+}
@@ -0,0 +1,156 @@
+package egoscale
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	defer setenv(map[string]string{
+		"CLOUDSTACK_KEY":    "KEY",
+		"CLOUDSTACK_SECRET": "SECRET",
+	})()
+
+	p := &EnvCredentials{}
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIKey != "KEY" || creds.APISecret != "SECRET" {
+		t.Errorf("bad credentials, got %#v", creds)
+	}
+
+	os.Setenv("CLOUDSTACK_KEY", "NEWKEY")
+	creds, err = p.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIKey != "NEWKEY" {
+		t.Errorf("the new key was expected, got %q", creds.APIKey)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	path, clean := writeConfig(t, "cloudstack.ini", "[cloudstack]\nkey = KEY\nsecret = SECRET\n")
+	defer clean()
+
+	p := NewFileCredentials(path, "")
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIKey != "KEY" {
+		t.Errorf("bad credentials, got %#v", creds)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("[cloudstack]\nkey = NEWKEY\nsecret = NEWSECRET\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err = p.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIKey != "NEWKEY" || creds.APISecret != "NEWSECRET" {
+		t.Errorf("the file should have been reloaded, got %#v", creds)
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required")
+	}
+
+	p := NewCommandCredentials(time.Hour, "sh", "-c", `echo '{"api_key": "KEY", "secret": "SECRET"}'`)
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIKey != "KEY" || creds.APISecret != "SECRET" {
+		t.Errorf("bad credentials, got %#v", creds)
+	}
+
+	p = NewCommandCredentials(0, "sh", "-c", "exit 1")
+	if _, err := p.Credentials(context.Background()); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestRotateKeys(t *testing.T) {
+	ts := newServer(response{200, `
+{"listaccountsresponse": {
+	"count": 1,
+	"account": [{
+		"id": "1",
+		"name": "test",
+		"user": [
+			{"id": "10", "apikey": "OTHER"},
+			{"id": "11", "apikey": "KEY"}
+		]
+	}]
+}}`}, response{200, `
+{"registeruserkeysresponse": {
+	"userkeys": {
+		"apikey": "NEWKEY",
+		"secretkey": "NEWSECRET"
+	}
+}}`})
+	defer ts.Close()
+
+	var users []string
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Interceptors = []Interceptor{{
+		BeforeSign: func(ctx context.Context, command string, params url.Values) error {
+			if command == "registerUserKeys" {
+				users = append(users, params.Get("id"))
+			}
+			return nil
+		},
+	}}
+
+	keys, err := cs.RotateKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys.APIKey != "NEWKEY" || key(cs) != "NEWKEY" || secret(cs) != "NEWSECRET" {
+		t.Errorf("the new keys should have been swapped in, got %q", key(cs))
+	}
+	if len(users) != 1 || users[0] != "11" {
+		t.Errorf("the keys of the user 11 were expected to be registered, got %v", users)
+	}
+}
+
+func TestRotateKeysNotStatic(t *testing.T) {
+	ts := newServer()
+	defer ts.Close()
+
+	sent := false
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.SetCredentialsProvider(&EnvCredentials{})
+	cs.Interceptors = []Interceptor{{
+		BeforeSign: func(ctx context.Context, command string, params url.Values) error {
+			sent = true
+			return nil
+		},
+	}}
+
+	if _, err := cs.RotateKeys(context.Background()); err == nil {
+		t.Errorf("an error was expected")
+	}
+	if sent {
+		t.Errorf("no requests should have been sent")
+	}
+	if _, ok := cs.CredentialsProvider().(*EnvCredentials); !ok {
+		t.Errorf("the provider should have been kept, got %T", cs.CredentialsProvider())
+	}
+}
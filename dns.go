@@ -1,33 +1,35 @@
 package egoscale
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // DNSDomain represents a domain
 type DNSDomain struct {
-	ID             int64  `json:"id"`
-	AccountID      int64  `json:"account_id,omitempty"`
-	UserID         int64  `json:"user_id,omitempty"`
-	RegistrantID   int64  `json:"registrant_id,omitempty"`
-	Name           string `json:"name"`
-	UnicodeName    string `json:"unicode_name"`
-	Token          string `json:"token"`
-	State          string `json:"state"`
-	Language       string `json:"language,omitempty"`
-	Lockable       bool   `json:"lockable"`
-	AutoRenew      bool   `json:"auto_renew"`
-	WhoisProtected bool   `json:"whois_protected"`
-	RecordCount    int64  `json:"record_count"`
-	ServiceCount   int64  `json:"service_count"`
-	ExpiresOn      string `json:"expires_on,omitempty"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	ID             int64      `json:"id"`
+	AccountID      int64      `json:"account_id,omitempty"`
+	UserID         int64      `json:"user_id,omitempty"`
+	RegistrantID   int64      `json:"registrant_id,omitempty"`
+	Name           string     `json:"name"`
+	UnicodeName    string     `json:"unicode_name"`
+	Token          string     `json:"token"`
+	State          string     `json:"state"`
+	Language       string     `json:"language,omitempty"`
+	Lockable       bool       `json:"lockable"`
+	AutoRenew      bool       `json:"auto_renew"`
+	WhoisProtected bool       `json:"whois_protected"`
+	RecordCount    int64      `json:"record_count"`
+	ServiceCount   int64      `json:"service_count"`
+	ExpiresOn      *Timestamp `json:"expires_on,omitempty"`
+	CreatedAt      *Timestamp `json:"created_at,omitempty"`
+	UpdatedAt      *Timestamp `json:"updated_at,omitempty"`
 }
 
 // DNSDomainResponse represents a domain creation response
@@ -37,15 +39,15 @@ type DNSDomainResponse struct {
 
 // DNSRecord represents a DNS record
 type DNSRecord struct {
-	ID         int64  `json:"id,omitempty"`
-	DomainID   int64  `json:"domain_id,omitempty"`
-	Name       string `json:"name"`
-	TTL        int    `json:"ttl,omitempty"`
-	CreatedAt  string `json:"created_at,omitempty"`
-	UpdatedAt  string `json:"updated_at,omitempty"`
-	Content    string `json:"content"`
-	RecordType string `json:"record_type"`
-	Prio       int    `json:"prio,omitempty"`
+	ID         int64      `json:"id,omitempty"`
+	DomainID   int64      `json:"domain_id,omitempty"`
+	Name       string     `json:"name"`
+	TTL        int        `json:"ttl,omitempty"`
+	CreatedAt  *Timestamp `json:"created_at,omitempty"`
+	UpdatedAt  *Timestamp `json:"updated_at,omitempty"`
+	Content    string     `json:"content"`
+	RecordType string     `json:"record_type"`
+	Prio       int        `json:"prio,omitempty"`
 }
 
 // DNSRecordResponse represents the creation of a DNS record
@@ -210,27 +212,66 @@ func (exo *Client) DeleteRecord(name string, recordID int64) error {
 	return err
 }
 
-func (exo *Client) dnsRequest(uri string, params string, method string) (json.RawMessage, error) {
-	url := exo.endpoint + uri
+func (exo *Client) dnsRequest(uri string, params string, method string) (body json.RawMessage, err error) {
+	endpoint := exo.endpoint
+	if exo.dnsEndpoint != "" {
+		endpoint = exo.dnsEndpoint
+	}
+
+	command := method + " " + uri
+	if exo.DryRun && method != "GET" {
+		exo.log(LevelInfo, "[DRYRUN]", Field{"command", command}, Field{"body", params})
+		return nil, ErrDryRun
+	}
+
+	var header http.Header
+	status := 0
+	start := time.Now()
+	ctx, span := exo.startSpan(context.Background(), "egoscale.http", command)
+	defer func() {
+		span.SetAttribute("status", status)
+		endSpan(span, err)
+		fields := []Field{
+			{"command", command},
+			{"header", header},
+			{"duration", time.Since(start)},
+			{"status", status},
+		}
+		exo.log(LevelDebug, "dnsRequest", append(fields, errorFields(err)...)...)
+	}()
+
+	url := endpoint + uri
 	req, err := http.NewRequest(method, url, strings.NewReader(params))
 	if err != nil {
 		return nil, err
 	}
 
+	creds, err := exo.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var hdr = make(http.Header)
-	hdr.Add("X-DNS-TOKEN", exo.apiKey+":"+exo.apiSecret)
+	hdr.Add("X-DNS-TOKEN", creds.APIKey+":"+creds.APISecret)
 	hdr.Add("Accept", "application/json")
 	if params != "" {
 		hdr.Add("Content-Type", "application/json")
 	}
 	req.Header = hdr
+	header = hdr
+	req = req.WithContext(ctx)
+
+	if err := exo.afterSign(ctx, command, req); err != nil {
+		return nil, err
+	}
 
-	response, err := exo.client.Do(req)
+	response, err := exo.do(ctx, command, req)
 	if err != nil {
 		return nil, err
 	}
 
 	defer response.Body.Close()
+	status = response.StatusCode
 	b, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return nil, err
@@ -8,6 +8,22 @@ To build a request, construct the adequate struct. This library expects a pointe
 
 Then everything within the struct is not a pointer. Find below some examples of how egoscale may be used to interact with a CloudStack endpoint, especially Exoscale itself. If anything feels odd or unclear, please let us know: https://github.com/exoscale/egoscale/issues
 
+Configuration
+
+The client may be configured by the environment, e.g. EXOSCALE_KEY and EXOSCALE_SECRET, or by a configuration file having many profiles, either cloudstack.ini or exoscale.toml.
+
+	cs, err := egoscale.NewClientFromEnv()
+
+	cs, err := egoscale.NewClientFromConfig(os.ExpandEnv("$HOME/.cloudstack.ini"), "production")
+
+The credentials are given by a CredentialsProvider, read for every request. Besides the static one, they may come from the environment, a configuration file (reloaded when modified) or an external command.
+
+	cs.SetCredentialsProvider(egoscale.NewFileCredentials(path, "production"))
+
+With static credentials, the keys of the current user may be rotated on a live client.
+
+	keys, err := cs.RotateKeys(ctx)
+
 APIs
 
 All the available APIs on the server and provided by the API Discovery plugin
@@ -109,6 +125,147 @@ An Elastic IP is a way to attach an IP address to many Virtual Machines. The API
 
 See: http://docs.cloudstack.apache.org/projects/cloudstack-administration/en/latest/networking_and_traffic.html#about-elastic-ips
 
+Asynchronous Jobs
+
+Async commands, like DeployVirtualMachine, are waited upon by Request. They may also be submitted without waiting, giving back a handle on the job.
+
+	job, err := cs.Submit(&egoscale.DeployVirtualMachine{
+		ServiceOfferingID: "...",
+		TemplateID: "...",
+		ZoneID: "...",
+	})
+
+	// ...
+
+	if err := job.Wait(ctx); err != nil {
+		panic(err)
+	}
+
+	resp := new(egoscale.DeployVirtualMachineResponse)
+	err = job.Result(resp)
+
+A job handle can also be rebuilt from its ID, e.g. after a restart.
+
+	job := cs.ResumeAsyncJob(jobID)
+
+The jobs are polled following the RetryStrategy of the client, which may be overridden per command. Short jobs can be polled often while the long ones back off gently.
+
+	cs.RetryStrategy = egoscale.Capped(10*time.Second, egoscale.FibonacciRetryStrategy)
+	cs.RetryStrategies = map[string]egoscale.RetryStrategyFunc{
+		"createTags":           egoscale.ConstantRetryStrategy(500 * time.Millisecond),
+		"deployVirtualMachine": egoscale.FullJitterRetryStrategy(2*time.Second, 30*time.Second),
+		"createSnapshot":       egoscale.DecorrelatedJitterRetryStrategy(5*time.Second, time.Minute),
+	}
+
+Logging
+
+Every API call is reported to the Logger of the client: the command, its parameters, the duration, the HTTP status, the job ID, the number of polls and the error codes. The secrets, like the apikey, the signature, the passwords or the userdata, are redacted beforehand. The DefaultLogger only writes what's above LevelInfo using the standard log package.
+
+	cs.Logger = &egoscale.StdLogger{Level: egoscale.LevelDebug}
+	// [DEBUG] request command=listZones params="apikey=[REDACTED]&command=listZones&response=json" duration=42ms status=200 attempts=1
+
+	cs.Logger = egoscale.NopLogger{}
+
+Metrics
+
+The Metrics of the client receives the duration and the error of every request, as well as how long the async jobs were waited upon and how many times they were polled, by command. The metrics package exports them in the Prometheus text format.
+
+	registry := metrics.NewRegistry()
+	cs.Metrics = registry
+	http.Handle("/metrics", registry)
+
+Tracing
+
+With a Tracer, the client opens a span per logical operation, e.g. egoscale.Request, egoscale.Paginate or egoscale.Wait, and a child egoscale.http span per HTTP call. They carry the command, page, jobid and jobstatus attributes. The spans are propagated through the context, an OpenTelemetry adapter fits in a few lines.
+
+	type otelTracer struct {
+		tracer trace.Tracer
+	}
+
+	func (t *otelTracer) Start(ctx context.Context, name string) (context.Context, egoscale.Span) {
+		ctx, span := t.tracer.Start(ctx, name)
+		return ctx, &otelSpan{span}
+	}
+
+Batches
+
+Batch runs many commands, e.g. stopping every VM of a zone, a few at a time and optionally at a limited rate. The responses come back in the order of the commands, the failures are gathered in a BatchError telling which command failed and why.
+
+	limiter, err := egoscale.NewRateLimiter(5, 10)
+	// ...
+	resps, err := cs.Batch(ctx, cmds, egoscale.BatchOptions{
+		Concurrency: 10,
+		RateLimiter: limiter,
+		StopOnError: true,
+	})
+	var batchErr *egoscale.BatchError
+	if errors.As(err, &batchErr) {
+		for _, e := range batchErr.Errors {
+			log.Printf("%s: %s", e.Command.APIName(), e.Err)
+		}
+	}
+
+Validation
+
+The commands are validated before being sent, according to the validate tag of their fields: required, oneof, excludes, min, max, cidr, ip, ipv4, ipv6 and protocol. The ValidationError reports every invalid field, and matches ErrInvalidParameter.
+
+	_, err := cs.Request(&egoscale.AuthorizeSecurityGroupIngress{
+		SecurityGroupName: "web",
+		Protocol:          "tcp",
+		StartPort:         80,
+		EndPort:           80,
+		CidrList:          []string{"10.0.0.1"},
+	})
+	// Invalid AuthorizeSecurityGroupIngress: CidrList must be a CIDR, got "10.0.0.1"
+
+Strict mode
+
+The responses are decoded leniently, the fields unknown to the structs are ignored. In Strict mode, they are reported with the type mismatches, as warnings to the Logger or as a DecodeError. It's a way to notice when the API changes, e.g. in the tests.
+
+	cs.Strict = egoscale.StrictLog
+	// [WARN] response drift command=listZones field=zone[0].newfield reason="is unknown"
+
+	cs.Strict = egoscale.StrictFail
+
+Dry run
+
+In DryRun mode, the client only sends the list*, get* and query* commands. The other ones are logged, with their parameters, instead. The sync commands fail with ErrDryRun while the async ones succeed with a synthetic result.
+
+	cs.DryRun = true
+	_, err := cs.Request(&egoscale.CreateSecurityGroup{Name: "web"})
+	// [INFO] [DRYRUN] command=createSecurityGroup params="name=web"
+	// err == egoscale.ErrDryRun
+
+Incoming requests
+
+Fake servers, proxies or audit tools can read the CloudStack requests they receive into the very same command structs.
+
+	if err := r.ParseForm(); err != nil {
+		...
+	}
+
+	cmd, err := egoscale.DecodeCommand(r.Form)
+	if err != nil {
+		...
+	}
+
+	if deploy, ok := cmd.(*egoscale.DeployVirtualMachine); ok {
+		...
+	}
+
+Custom commands are made known using RegisterCommand. The signature of the requests is checked using VerifySignature.
+
+	if err := egoscale.VerifySignature(r.Form, apiSecret); err != nil {
+		...
+	}
+
+Signed URLs
+
+A command can be turned into a GET URL, e.g. to be used with curl. With an Expiration, the requests are signed using the version 3 of the signature and the URL stops working after that long.
+
+	cs.Expiration = 10 * time.Minute
+	u, err := cs.SignedURL(&egoscale.ListZones{})
+
 
 
 */
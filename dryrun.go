@@ -0,0 +1,52 @@
+package egoscale
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDryRun is returned by the mutating sync commands when the client is in DryRun mode
+var ErrDryRun = errors.New("egoscale: dry run, the command was not sent")
+
+// readOnlyPrefixes are the commands which don't change anything, they're sent even in DryRun mode
+var readOnlyPrefixes = []string{"list", "get", "query"}
+
+// dryRunResult is the result of the simulated async jobs
+var dryRunResult = json.RawMessage(`{"success": true, "displaytext": "dry run"}`)
+
+// IsReadOnly tells whether the command doesn't change anything, e.g. listZones
+func IsReadOnly(command string) bool {
+	command = strings.ToLower(command)
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRun logs the command instead of sending it
+func (exo *Client) dryRun(command string, params url.Values) error {
+	exo.log(LevelInfo, "[DRYRUN]", Field{"command", command}, Field{"params", params})
+	return ErrDryRun
+}
+
+// dryRunJob builds the successfully completed job of a simulated async command
+func (exo *Client) dryRunJob(command string) *AsyncJob {
+	result := dryRunResult
+	return &AsyncJob{
+		Command: command,
+		Created: time.Now(),
+		client:  exo,
+		dryRun:  true,
+		result: &AsyncJobResult{
+			Cmd:           command,
+			JobStatus:     Success,
+			JobResult:     &result,
+			JobResultType: "object",
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package egoscale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	var commands []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		commands = append(commands, r.PostForm.Get("command"))
+		w.Write([]byte(`{"listzonesresponse": {"count": 1, "zone": [{"id": "1", "name": "ch-gva-2"}]}}`))
+	}))
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.DryRun = true
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cs.Request(&CreateSecurityGroup{Name: "web"}); err != ErrDryRun {
+		t.Errorf("ErrDryRun was expected, got %v", err)
+	}
+
+	resp, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.(*DeployVirtualMachineResponse); !ok {
+		t.Errorf("a synthetic response was expected, got %#v", resp)
+	}
+
+	if err := cs.BooleanRequest(&ExpungeVirtualMachine{ID: "1"}); err != nil {
+		t.Errorf("the async boolean commands should succeed, got %v", err)
+	}
+
+	if _, err := cs.CreateRecord("example.com", DNSRecord{Name: "www"}); err != ErrDryRun {
+		t.Errorf("ErrDryRun was expected, got %v", err)
+	}
+
+	if len(commands) != 1 || commands[0] != "listZones" {
+		t.Errorf("only listZones should have been sent, got %v", commands)
+	}
+}
+
+func TestDryRunStrict(t *testing.T) {
+	cs := NewClient("http://localhost", "KEY", "SECRET")
+	cs.DryRun = true
+	cs.Strict = StrictFail
+
+	_, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+	})
+	if err != nil {
+		t.Errorf("the simulated async commands should succeed in strict mode, got %v", err)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := map[string]bool{
+		"listZones":             true,
+		"ListVirtualMachines":   true,
+		"getVMPassword":         true,
+		"queryAsyncJobResult":   true,
+		"deployVirtualMachine":  false,
+		"destroyVirtualMachine": false,
+	}
+
+	for command, expected := range tests {
+		if IsReadOnly(command) != expected {
+			t.Errorf("%s: %v was expected", command, expected)
+		}
+	}
+}
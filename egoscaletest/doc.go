@@ -0,0 +1,45 @@
+/*
+
+Package egoscaletest provides utilities to test the code using egoscale without any network.
+
+Recorder
+
+The Recorder is an http.RoundTripper that records the real interactions with CloudStack and the DNS API into a cassette file, then replays them.
+
+	mode := egoscaletest.Replay
+	if !egoscaletest.Exists(cassette) {
+		mode = egoscaletest.Record
+	}
+
+	rec, err := egoscaletest.NewRecorder(cassette, mode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Save()
+
+	cs := egoscale.NewClient(endpoint, apiKey, apiSecret)
+	cs.HTTPClient.Transport = rec
+
+The apikey and the signature of the requests are never recorded.
+
+Server
+
+The Server is a fake CloudStack keeping its state in memory. It checks the
+signature of the requests and implements the lifecycle of the virtual machines,
+the security groups and their rules, the public IP addresses, the tags, the
+volumes and the snapshots, within a single zone.
+
+	s := egoscaletest.NewServer("KEY", "SECRET")
+	defer s.Close()
+
+	cs := s.NewClient()
+	resp, err := cs.Request(&egoscale.DeployVirtualMachine{
+		ServiceOfferingID: serviceOfferingID,
+		TemplateID:        templateID,
+		ZoneID:            egoscaletest.DefaultZoneID,
+	})
+
+The async jobs are pending until they've been polled PendingPolls times.
+
+*/
+package egoscaletest
@@ -0,0 +1,304 @@
+package egoscaletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode represents the mode of a Recorder
+type Mode int
+
+const (
+	// Replay serves the interactions of the cassette, without any network
+	Replay Mode = iota
+	// Record sends the requests for real and captures the interactions
+	Record
+)
+
+// Cassette represents a recorded set of HTTP interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction represents one HTTP exchange
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request represents a recorded HTTP request
+//
+// A CloudStack request is described by its command and its parameters, the
+// credentials (apikey, signature) being removed. A DNS request is described
+// by its method, path and body.
+type Request struct {
+	Method  string     `json:"method"`
+	Path    string     `json:"path"`
+	Command string     `json:"command,omitempty"`
+	Params  url.Values `json:"params,omitempty"`
+	Body    string     `json:"body,omitempty"`
+}
+
+// Response represents a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// scrubbedParams are removed from the recorded requests, and ignored when matching them
+//
+// The expiration of the signature changes at every run.
+var scrubbedParams = []string{"apikey", "signature", "signatureversion", "expires"}
+
+// recordedHeaders are the response headers kept in the cassette
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Recorder is an http.RoundTripper recording and replaying HTTP interactions
+//
+//	rec, err := egoscaletest.NewRecorder("testdata/vm.json", egoscaletest.Replay, nil)
+//	cs := egoscale.NewClient(endpoint, apiKey, apiSecret)
+//	cs.HTTPClient.Transport = rec
+//
+// In replay mode, the requests are matched against the recorded ones, in order.
+// When the same request was recorded many times, e.g. polling an async job,
+// the responses are served one after the other, the last one being repeated.
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewRecorder creates a recorder using the cassette at path
+//
+// In record mode, the requests are sent using transport, or
+// http.DefaultTransport, and the cassette is written by Save.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: transport,
+		cassette:  &Cassette{Interactions: make([]Interaction, 0)},
+	}
+
+	if mode == Replay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, r.cassette); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// RoundTrip records or replays the given request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, body, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == Replay {
+		interaction, err := r.find(recorded)
+		if err != nil {
+			return nil, err
+		}
+		return interaction.Response.http(req), nil
+	}
+
+	// the body was consumed, it has to be given back to the real transport
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := Response{
+		StatusCode: resp.StatusCode,
+		Header:     make(http.Header),
+		Body:       string(b),
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			response.Header.Set(h, v)
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  *recorded,
+		Response: response,
+	})
+	r.mu.Unlock()
+
+	return response.http(req), nil
+}
+
+// Save writes the recorded interactions into the cassette file
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, append(b, '\n'), 0644)
+}
+
+// Cassette returns the recorded interactions
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cassette
+}
+
+// find returns the first unused matching interaction, or the last matching one
+func (r *Recorder) find(req *Request) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i := range r.cassette.Interactions {
+		if !r.cassette.Interactions[i].Request.matches(req) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.cassette.Interactions[i], nil
+		}
+		last = i
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("egoscaletest: no interaction recorded for %s", req)
+	}
+	return &r.cassette.Interactions[last], nil
+}
+
+// newRequest describes the HTTP request, the body is returned as it had to be read
+func newRequest(req *http.Request) (*Request, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		body = b
+	}
+
+	r := &Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+
+	params := req.URL.Query()
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range values {
+			params[k] = v
+		}
+	} else {
+		r.Body = string(body)
+	}
+
+	if command := params.Get("command"); command != "" {
+		params.Del("command")
+		for _, k := range scrubbedParams {
+			params.Del(k)
+		}
+		r.Command = command
+		r.Params = params
+	} else if len(params) > 0 {
+		r.Params = params
+	}
+
+	return r, body, nil
+}
+
+// matches tells whether both requests are the same, the parameters are compared regardless of their order
+func (r *Request) matches(other *Request) bool {
+	return r.Method == other.Method &&
+		r.Path == other.Path &&
+		r.Command == other.Command &&
+		r.Body == other.Body &&
+		normalize(r.Params) == normalize(other.Params)
+}
+
+// String formats the request
+func (r *Request) String() string {
+	if r.Command != "" {
+		return fmt.Sprintf("%s %s", r.Command, normalize(r.Params))
+	}
+	return fmt.Sprintf("%s %s %s", r.Method, r.Path, r.Body)
+}
+
+// normalize encodes the parameters, sorted by key and case insensitive
+func normalize(params url.Values) string {
+	values := url.Values{}
+	for k, v := range params {
+		values[strings.ToLower(k)] = v
+	}
+	return values.Encode()
+}
+
+// http builds the HTTP response
+func (r *Response) http(req *http.Request) *http.Response {
+	header := make(http.Header)
+	for k, v := range r.Header {
+		header[k] = v
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// Exists tells whether the cassette file exists, handy to switch between modes
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
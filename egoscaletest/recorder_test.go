@@ -0,0 +1,134 @@
+package egoscaletest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exoscale/egoscale"
+)
+
+func TestReplay(t *testing.T) {
+	rec, err := NewRecorder("testdata/zones.json", Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := egoscale.NewClient("http://127.0.0.1:1/compute", "KEY", "SECRET")
+	cs.HTTPClient.Transport = rec
+
+	resp, err := cs.Request(&egoscale.ListZones{Name: "ch-gva-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zones := resp.(*egoscale.ListZonesResponse)
+	if zones.Count != 1 || zones.Zone[0].Name != "ch-gva-2" {
+		t.Errorf("bad zones, got %#v", zones)
+	}
+
+	if _, err := cs.Request(&egoscale.ListZones{Name: "de-fra-1"}); err == nil {
+		t.Errorf("an error was expected, the request wasn't recorded")
+	}
+}
+
+func TestReplayExpiration(t *testing.T) {
+	rec, err := NewRecorder("testdata/zones.json", Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := egoscale.NewClient("http://127.0.0.1:1/compute", "KEY", "SECRET")
+	cs.HTTPClient.Transport = rec
+	cs.Expiration = time.Minute
+
+	resp, err := cs.Request(&egoscale.ListZones{Name: "ch-gva-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zones := resp.(*egoscale.ListZonesResponse)
+	if zones.Count != 1 || zones.Zone[0].Name != "ch-gva-2" {
+		t.Errorf("bad zones, got %#v", zones)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	i := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compute", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.PostForm.Get("command") {
+		case "expungeVirtualMachine":
+			w.Write([]byte(`{"expungevirtualmachineresponse": {"jobid": "1", "jobstatus": 0}}`))
+		case "queryAsyncJobResult":
+			i++
+			if i < 2 {
+				w.Write([]byte(`{"queryasyncjobresultresponse": {"jobid": "1", "jobstatus": 0}}`))
+				return
+			}
+			w.Write([]byte(`{"queryasyncjobresultresponse": {"jobid": "1", "jobstatus": 1, "jobresult": {"success": true}}}`))
+		default:
+			w.WriteHeader(400)
+		}
+	})
+	mux.HandleFunc("/dns/v1/domains/example.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"domain": {"id": 1, "name": "example.com"}}`))
+	})
+	ts := httptest.NewServer(mux)
+
+	dir, err := ioutil.TempDir("", "egoscaletest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassette := filepath.Join(dir, "cassette.json")
+
+	run := func(mode Mode, endpoint string) {
+		rec, err := NewRecorder(cassette, mode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cs := egoscale.NewClient(endpoint+"/compute", "KEY", "SECRET")
+		cs.HTTPClient.Transport = rec
+		cs.RetryStrategy = egoscale.ConstantRetryStrategy(0)
+
+		if err := cs.BooleanRequest(&egoscale.ExpungeVirtualMachine{ID: "123"}); err != nil {
+			t.Fatal(err)
+		}
+
+		dns := egoscale.NewClient(endpoint+"/dns", "KEY", "SECRET")
+		dns.HTTPClient.Transport = rec
+		domain, err := dns.GetDomain("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if domain.Name != "example.com" {
+			t.Errorf("bad domain, got %#v", domain)
+		}
+
+		if err := rec.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run(Record, ts.URL)
+	ts.Close()
+
+	b, err := ioutil.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "KEY") || strings.Contains(string(b), "signature") {
+		t.Errorf("the credentials should have been scrubbed, got %s", b)
+	}
+
+	// the server is gone, everything comes from the cassette
+	run(Replay, "http://127.0.0.1:1")
+}
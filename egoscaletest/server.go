@@ -0,0 +1,402 @@
+package egoscaletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exoscale/egoscale"
+)
+
+// DefaultZoneID is the ID of the only zone of the Server
+const DefaultZoneID = "1128bd56-b4d9-4ac6-a7b9-c715b187ce11"
+
+// DefaultZoneName is the name of the only zone of the Server
+const DefaultZoneName = "ch-gva-2"
+
+// Server is a fake CloudStack endpoint, keeping its state in memory
+//
+// It verifies the signature of the requests and implements the commands
+// to manage the virtual machines, security groups, public IP addresses,
+// tags, volumes and snapshots. The async commands return a job which
+// completes after PendingPolls calls to queryAsyncJobResult.
+//
+//	s := egoscaletest.NewServer("KEY", "SECRET")
+//	defer s.Close()
+//
+//	cs := s.NewClient()
+type Server struct {
+	// URL is the endpoint of the fake CloudStack
+	URL string
+	// PendingPolls represents how many times an async job is seen as pending
+	PendingPolls int
+
+	apiKey    string
+	apiSecret string
+	server    *httptest.Server
+
+	mu        sync.Mutex
+	sequence  int
+	vms       map[string]*egoscale.VirtualMachine
+	sgs       map[string]*egoscale.SecurityGroup
+	ips       map[string]*egoscale.IPAddress
+	volumes   map[string]*egoscale.Volume
+	snapshots map[string]*egoscale.Snapshot
+	tags      []egoscale.ResourceTag
+	jobs      map[string]*job
+}
+
+// job represents an async job, its action runs when it completes
+type job struct {
+	result egoscale.AsyncJobResult
+	polls  int
+	action func() (interface{}, error)
+}
+
+// apiError represents an error returned by CloudStack
+type apiError struct {
+	code   egoscale.ErrorCode
+	csCode egoscale.CSErrorCode
+	text   string
+}
+
+// Error formats the error
+func (e *apiError) Error() string {
+	return e.text
+}
+
+// paramError builds a ParamError
+func paramError(format string, args ...interface{}) error {
+	return &apiError{
+		code:   egoscale.ParamError,
+		csCode: egoscale.InvalidParameterValueException,
+		text:   fmt.Sprintf(format, args...),
+	}
+}
+
+// notFound builds the error CloudStack gives for an unknown ID
+func notFound(name, id string) error {
+	return paramError("Unable to execute API command due to invalid value. Invalid parameter %s value=%s due to incorrect long value format, or entity does not exist or due to incorrect parameter annotation for the field in api cmd class.", name, id)
+}
+
+// NewServer starts a fake CloudStack accepting the given credentials
+func NewServer(apiKey, apiSecret string) *Server {
+	s := &Server{
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		vms:       make(map[string]*egoscale.VirtualMachine),
+		sgs:       make(map[string]*egoscale.SecurityGroup),
+		ips:       make(map[string]*egoscale.IPAddress),
+		volumes:   make(map[string]*egoscale.Volume),
+		snapshots: make(map[string]*egoscale.Snapshot),
+		tags:      make([]egoscale.ResourceTag, 0),
+		jobs:      make(map[string]*job),
+	}
+
+	id := s.nextID()
+	s.sgs[id] = &egoscale.SecurityGroup{
+		ID:          id,
+		Name:        "default",
+		Description: "Default Security Group",
+		IngressRule: []egoscale.IngressRule{},
+		EgressRule:  []egoscale.EgressRule{},
+	}
+
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// NewClient creates a client talking to the server, it polls the jobs without waiting
+func (s *Server) NewClient() *egoscale.Client {
+	cs := egoscale.NewClient(s.URL, s.apiKey, s.apiSecret)
+	cs.RetryStrategy = egoscale.ConstantRetryStrategy(time.Millisecond)
+	return cs
+}
+
+// ServeHTTP handles a CloudStack request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := r.Form
+	command := params.Get("command")
+	if command == "" {
+		http.Error(w, "command is missing", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.verify(params); err != nil {
+		s.write(w, command, nil, err)
+		return
+	}
+
+	if !known(command) {
+		s.write(w, command, nil, &apiError{
+			code:   egoscale.Unauthorized,
+			csCode: egoscale.ServerAPIException,
+			text:   "The given command does not exist or it is not available for user",
+		})
+		return
+	}
+
+	// the parameters are checked against the command struct, like CloudStack does
+	if _, err := egoscale.DecodeCommand(params); err != nil {
+		s.write(w, command, nil, paramError("%s", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if command == "queryAsyncJobResult" {
+		resp, err := s.queryAsyncJobResult(params)
+		s.write(w, command, resp, err)
+		return
+	}
+
+	if handler, ok := syncCommands[command]; ok {
+		resp, err := handler(s, params)
+		s.write(w, command, resp, err)
+		return
+	}
+
+	action, err := asyncCommands[command](s, params)
+	if err != nil {
+		s.write(w, command, nil, err)
+		return
+	}
+	s.write(w, command, s.submit(command, action), nil)
+}
+
+// known tells whether the server implements the command
+func known(command string) bool {
+	_, sync := syncCommands[command]
+	_, async := asyncCommands[command]
+	return sync || async || command == "queryAsyncJobResult"
+}
+
+// verify checks the API key and the signature of the request
+func (s *Server) verify(params url.Values) error {
+	if params.Get("apikey") != s.apiKey || egoscale.VerifySignature(params, s.apiSecret) != nil {
+		return &apiError{
+			code:   egoscale.Unauthorized,
+			csCode: egoscale.CloudAuthenticationException,
+			text:   "unable to verify user credentials and/or request signature",
+		}
+	}
+	return nil
+}
+
+// write sends the response, or the error, wrapped the CloudStack way
+func (s *Server) write(w http.ResponseWriter, command string, resp interface{}, err error) {
+	status := http.StatusOK
+	if err != nil {
+		e, ok := err.(*apiError)
+		if !ok {
+			e = &apiError{code: egoscale.InternalError, csCode: egoscale.CloudRuntimeException, text: err.Error()}
+		}
+		status = int(e.code)
+		resp = &egoscale.ErrorResponse{
+			ErrorCode:   e.code,
+			CsErrorCode: e.csCode,
+			ErrorText:   e.text,
+		}
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		strings.ToLower(command) + "response": resp,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// submit creates a pending job
+func (s *Server) submit(command string, action func() (interface{}, error)) interface{} {
+	id := s.nextID()
+	s.jobs[id] = &job{
+		result: egoscale.AsyncJobResult{
+			JobID:     id,
+			Cmd:       command,
+			Created:   &egoscale.Timestamp{Time: time.Now()},
+			JobStatus: egoscale.Pending,
+		},
+		action: action,
+	}
+
+	return map[string]string{"jobid": id}
+}
+
+// queryAsyncJobResult gives the status of a job, completing it after PendingPolls calls
+func (s *Server) queryAsyncJobResult(params url.Values) (interface{}, error) {
+	id := params.Get("jobid")
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, notFound("jobid", id)
+	}
+
+	if j.result.JobStatus == egoscale.Pending {
+		j.polls++
+		if j.polls > s.PendingPolls {
+			s.complete(j)
+		}
+	}
+
+	return j.result, nil
+}
+
+// complete runs the action of the job and stores its result
+func (s *Server) complete(j *job) {
+	resp, err := j.action()
+	if err != nil {
+		e, ok := err.(*apiError)
+		if !ok {
+			e = &apiError{code: egoscale.InternalError, csCode: egoscale.CloudRuntimeException, text: err.Error()}
+		}
+		resp = &egoscale.ErrorResponse{
+			ErrorCode:   e.code,
+			CsErrorCode: e.csCode,
+			ErrorText:   e.text,
+		}
+		j.result.JobStatus = egoscale.Failure
+		j.result.JobResultCode = int(e.code)
+	} else {
+		j.result.JobStatus = egoscale.Success
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		panic(err)
+	}
+	raw := json.RawMessage(b)
+	j.result.JobResult = &raw
+	j.result.JobResultType = "object"
+}
+
+// nextID returns a new unique identifier
+func (s *Server) nextID() string {
+	s.sequence++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.sequence)
+}
+
+// page returns the requested page of the n items, as a range
+func page(params url.Values, n int) (int, int) {
+	pageSize, _ := strconv.Atoi(params.Get("pagesize"))
+	p, _ := strconv.Atoi(params.Get("page"))
+	if pageSize <= 0 {
+		return 0, n
+	}
+	if p <= 0 {
+		p = 1
+	}
+
+	start := (p - 1) * pageSize
+	if start > n {
+		start = n
+	}
+	end := start + pageSize
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// indexed reads the list of maps serialized as prefix[0].key=value
+func indexed(params url.Values, prefix string) []map[string]string {
+	items := make(map[int]map[string]string)
+	max := -1
+	for k, v := range params {
+		if !strings.HasPrefix(k, prefix+"[") {
+			continue
+		}
+		rest := k[len(prefix)+1:]
+		end := strings.Index(rest, "].")
+		if end < 0 {
+			continue
+		}
+		i, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			continue
+		}
+		if items[i] == nil {
+			items[i] = make(map[string]string)
+		}
+		items[i][rest[end+2:]] = v[0]
+		if i > max {
+			max = i
+		}
+	}
+
+	list := make([]map[string]string, 0, len(items))
+	for i := 0; i <= max; i++ {
+		if item, ok := items[i]; ok {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// list reads a comma separated list
+func list(params url.Values, name string) []string {
+	v := params.Get(name)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// require returns the parameter or an error if it's missing
+func require(params url.Values, name string) (string, error) {
+	v := params.Get(name)
+	if v == "" {
+		return "", paramError("Unable to execute API command due to missing parameter %s", name)
+	}
+	return v, nil
+}
+
+// matchesTags tells whether the resource has all the tags[n].key=value given
+func (s *Server) matchesTags(params url.Values, resourceID string) bool {
+	for _, tag := range indexed(params, "tags") {
+		found := false
+		for _, t := range s.resourceTags(resourceID) {
+			if t.Key == tag["key"] && t.Value == tag["value"] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceTags returns the tags of the given resource
+func (s *Server) resourceTags(resourceID string) []egoscale.ResourceTag {
+	tags := make([]egoscale.ResourceTag, 0)
+	for _, t := range s.tags {
+		if t.ResourceID == resourceID {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
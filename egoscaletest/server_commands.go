@@ -0,0 +1,830 @@
+package egoscaletest
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/exoscale/egoscale"
+)
+
+// syncHandler executes a sync command
+type syncHandler func(s *Server, params url.Values) (interface{}, error)
+
+// asyncHandler validates an async command and returns the action to run once the job completes
+type asyncHandler func(s *Server, params url.Values) (func() (interface{}, error), error)
+
+var syncCommands map[string]syncHandler
+var asyncCommands map[string]asyncHandler
+
+func init() {
+	syncCommands = map[string]syncHandler{
+		"listZones":             (*Server).listZones,
+		"listVirtualMachines":   (*Server).listVirtualMachines,
+		"createSecurityGroup":   (*Server).createSecurityGroup,
+		"deleteSecurityGroup":   (*Server).deleteSecurityGroup,
+		"listSecurityGroups":    (*Server).listSecurityGroups,
+		"listPublicIpAddresses": (*Server).listPublicIPAddresses,
+		"listTags":              (*Server).listTags,
+		"listVolumes":           (*Server).listVolumes,
+		"listSnapshots":         (*Server).listSnapshots,
+	}
+
+	asyncCommands = map[string]asyncHandler{
+		"deployVirtualMachine":          (*Server).deployVirtualMachine,
+		"startVirtualMachine":           (*Server).startVirtualMachine,
+		"stopVirtualMachine":            (*Server).stopVirtualMachine,
+		"rebootVirtualMachine":          (*Server).rebootVirtualMachine,
+		"destroyVirtualMachine":         (*Server).destroyVirtualMachine,
+		"expungeVirtualMachine":         (*Server).expungeVirtualMachine,
+		"authorizeSecurityGroupIngress": (*Server).authorizeSecurityGroupIngress,
+		"authorizeSecurityGroupEgress":  (*Server).authorizeSecurityGroupEgress,
+		"revokeSecurityGroupIngress":    (*Server).revokeSecurityGroupIngress,
+		"revokeSecurityGroupEgress":     (*Server).revokeSecurityGroupEgress,
+		"associateIpAddress":            (*Server).associateIPAddress,
+		"disassociateIpAddress":         (*Server).disassociateIPAddress,
+		"createTags":                    (*Server).createTags,
+		"deleteTags":                    (*Server).deleteTags,
+		"resizeVolume":                  (*Server).resizeVolume,
+		"createSnapshot":                (*Server).createSnapshot,
+		"deleteSnapshot":                (*Server).deleteSnapshot,
+		"revertSnapshot":                (*Server).revertSnapshot,
+	}
+}
+
+// success is the result of the boolean commands
+var success = map[string]interface{}{"success": true}
+
+// sortedKeys returns the keys of the map, sorted, for the listings to be stable
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch m := m.(type) {
+	case map[string]*egoscale.VirtualMachine:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*egoscale.SecurityGroup:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*egoscale.IPAddress:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*egoscale.Volume:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*egoscale.Snapshot:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) listZones(params url.Values) (interface{}, error) {
+	zones := make([]egoscale.Zone, 0, 1)
+	if id := params.Get("id"); id == "" || id == DefaultZoneID {
+		if name := params.Get("name"); name == "" || name == DefaultZoneName {
+			zones = append(zones, egoscale.Zone{ID: DefaultZoneID, Name: DefaultZoneName})
+		}
+	}
+
+	return egoscale.ListZonesResponse{Count: len(zones), Zone: zones}, nil
+}
+
+// Virtual Machines
+
+func (s *Server) vm(id string) (*egoscale.VirtualMachine, error) {
+	vm, ok := s.vms[id]
+	if !ok {
+		return nil, notFound("id", id)
+	}
+	return vm, nil
+}
+
+func (s *Server) vmResponse(vm *egoscale.VirtualMachine) egoscale.VirtualMachineResponse {
+	v := *vm
+	v.Tags = s.resourceTags(vm.ID)
+	return egoscale.VirtualMachineResponse{VirtualMachine: v}
+}
+
+func (s *Server) listVirtualMachines(params url.Values) (interface{}, error) {
+	vms := make([]egoscale.VirtualMachine, 0)
+	for _, id := range sortedKeys(s.vms) {
+		vm := s.vms[id]
+		if v := params.Get("id"); v != "" && v != vm.ID {
+			continue
+		}
+		if v := params.Get("name"); v != "" && v != vm.Name {
+			continue
+		}
+		if v := params.Get("state"); v != "" && v != vm.State {
+			continue
+		}
+		if v := params.Get("zoneid"); v != "" && v != vm.ZoneID {
+			continue
+		}
+		if v := params.Get("ipaddress"); v != "" && (len(vm.Nic) == 0 || vm.Nic[0].IPAddress.String() != v) {
+			continue
+		}
+		if !s.matchesTags(params, vm.ID) {
+			continue
+		}
+		vms = append(vms, s.vmResponse(vm).VirtualMachine)
+	}
+
+	start, end := page(params, len(vms))
+	return egoscale.ListVirtualMachinesResponse{Count: len(vms), VirtualMachine: vms[start:end]}, nil
+}
+
+func (s *Server) deployVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	serviceOfferingID, err := require(params, "serviceofferingid")
+	if err != nil {
+		return nil, err
+	}
+	templateID, err := require(params, "templateid")
+	if err != nil {
+		return nil, err
+	}
+	zoneID, err := require(params, "zoneid")
+	if err != nil {
+		return nil, err
+	}
+	if zoneID != DefaultZoneID {
+		return nil, notFound("zoneid", zoneID)
+	}
+
+	sgs := make([]egoscale.SecurityGroup, 0)
+	for _, id := range list(params, "securitygroupids") {
+		sg, ok := s.sgs[id]
+		if !ok {
+			return nil, notFound("securitygroupids", id)
+		}
+		sgs = append(sgs, *sg)
+	}
+	for _, name := range list(params, "securitygroupnames") {
+		sg := s.securityGroupByName(name)
+		if sg == nil {
+			return nil, paramError("Unable to find group by name %s", name)
+		}
+		sgs = append(sgs, *sg)
+	}
+	if len(sgs) == 0 {
+		sgs = append(sgs, *s.securityGroupByName("default"))
+	}
+
+	return func() (interface{}, error) {
+		id := s.nextID()
+		name := params.Get("name")
+		if name == "" {
+			name = "VM-" + id
+		}
+		displayName := params.Get("displayname")
+		if displayName == "" {
+			displayName = name
+		}
+
+		vm := &egoscale.VirtualMachine{
+			ID:                id,
+			Name:              name,
+			DisplayName:       displayName,
+			State:             "Running",
+			ServiceOfferingID: serviceOfferingID,
+			TemplateID:        templateID,
+			ZoneID:            zoneID,
+			ZoneName:          DefaultZoneName,
+			KeyPair:           params.Get("keypair"),
+			SecurityGroup:     sgs,
+			Nic: []egoscale.Nic{{
+				ID:        s.nextID(),
+				IPAddress: net.IPv4(185, 19, 28, byte(s.sequence%250+1)),
+				IsDefault: true,
+				Type:      "Shared",
+			}},
+		}
+		s.vms[id] = vm
+
+		volumeID := s.nextID()
+		size, _ := strconv.ParseUint(params.Get("rootdisksize"), 10, 64)
+		if size == 0 {
+			size = 10
+		}
+		s.volumes[volumeID] = &egoscale.Volume{
+			ID:               volumeID,
+			Name:             "ROOT-" + id,
+			Type:             "ROOT",
+			State:            "Ready",
+			Size:             size << 30,
+			VirtualMachineID: id,
+			VMName:           name,
+			ZoneID:           zoneID,
+			ZoneName:         DefaultZoneName,
+		}
+
+		return s.vmResponse(vm), nil
+	}, nil
+}
+
+// vmStateChange builds the handler changing the state of a VM
+func vmStateChange(from, to string) asyncHandler {
+	return func(s *Server, params url.Values) (func() (interface{}, error), error) {
+		id, err := require(params, "id")
+		if err != nil {
+			return nil, err
+		}
+		vm, err := s.vm(id)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() (interface{}, error) {
+			if from != "" && vm.State != from {
+				return nil, &apiError{
+					code:   egoscale.InternalError,
+					csCode: egoscale.InvalidParameterValueException,
+					text:   fmt.Sprintf("The virtual machine %s is %s, it should be %s", vm.ID, vm.State, from),
+				}
+			}
+			vm.State = to
+			return s.vmResponse(vm), nil
+		}, nil
+	}
+}
+
+func (s *Server) startVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	return vmStateChange("Stopped", "Running")(s, params)
+}
+
+func (s *Server) stopVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	return vmStateChange("Running", "Stopped")(s, params)
+}
+
+func (s *Server) rebootVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	return vmStateChange("Running", "Running")(s, params)
+}
+
+func (s *Server) destroyVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	action, err := vmStateChange("", "Destroyed")(s, params)
+	if err != nil || params.Get("expunge") != "true" {
+		return action, err
+	}
+
+	return func() (interface{}, error) {
+		resp, err := action()
+		if err == nil {
+			s.expunge(params.Get("id"))
+		}
+		return resp, err
+	}, nil
+}
+
+func (s *Server) expungeVirtualMachine(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.vm(id); err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, error) {
+		s.expunge(id)
+		return success, nil
+	}, nil
+}
+
+// expunge removes the VM and its volumes
+func (s *Server) expunge(id string) {
+	delete(s.vms, id)
+	for volumeID, volume := range s.volumes {
+		if volume.VirtualMachineID == id {
+			delete(s.volumes, volumeID)
+		}
+	}
+}
+
+// Security Groups
+
+func (s *Server) securityGroupByName(name string) *egoscale.SecurityGroup {
+	for _, sg := range s.sgs {
+		if sg.Name == name {
+			return sg
+		}
+	}
+	return nil
+}
+
+func (s *Server) securityGroup(params url.Values, idKey, nameKey string) (*egoscale.SecurityGroup, error) {
+	if id := params.Get(idKey); id != "" {
+		sg, ok := s.sgs[id]
+		if !ok {
+			return nil, notFound(idKey, id)
+		}
+		return sg, nil
+	}
+
+	name, err := require(params, nameKey)
+	if err != nil {
+		return nil, err
+	}
+	sg := s.securityGroupByName(name)
+	if sg == nil {
+		return nil, paramError("Unable to find security group %s", name)
+	}
+	return sg, nil
+}
+
+func (s *Server) securityGroupResponse(sg *egoscale.SecurityGroup) egoscale.SecurityGroupResponse {
+	group := *sg
+	group.Tags = s.resourceTags(sg.ID)
+	return egoscale.SecurityGroupResponse{SecurityGroup: group}
+}
+
+func (s *Server) createSecurityGroup(params url.Values) (interface{}, error) {
+	name, err := require(params, "name")
+	if err != nil {
+		return nil, err
+	}
+	if s.securityGroupByName(name) != nil {
+		return nil, paramError("The security group %s already exists", name)
+	}
+
+	id := s.nextID()
+	sg := &egoscale.SecurityGroup{
+		ID:          id,
+		Name:        name,
+		Description: params.Get("description"),
+		IngressRule: []egoscale.IngressRule{},
+		EgressRule:  []egoscale.EgressRule{},
+	}
+	s.sgs[id] = sg
+
+	return s.securityGroupResponse(sg), nil
+}
+
+func (s *Server) deleteSecurityGroup(params url.Values) (interface{}, error) {
+	sg, err := s.securityGroup(params, "id", "name")
+	if err != nil {
+		return nil, err
+	}
+	if sg.Name == "default" {
+		return nil, paramError("The default security group cannot be removed")
+	}
+	for _, vm := range s.vms {
+		for _, group := range vm.SecurityGroup {
+			if group.ID == sg.ID {
+				return nil, &apiError{
+					code:   egoscale.ResourceInUseError,
+					csCode: egoscale.ResourceInUseException,
+					text:   fmt.Sprintf("The security group %s is in use", sg.Name),
+				}
+			}
+		}
+	}
+
+	delete(s.sgs, sg.ID)
+	return map[string]string{"success": "true"}, nil
+}
+
+func (s *Server) listSecurityGroups(params url.Values) (interface{}, error) {
+	sgs := make([]egoscale.SecurityGroup, 0)
+	for _, id := range sortedKeys(s.sgs) {
+		sg := s.sgs[id]
+		if v := params.Get("id"); v != "" && v != sg.ID {
+			continue
+		}
+		if v := params.Get("securitygroupname"); v != "" && v != sg.Name {
+			continue
+		}
+		if !s.matchesTags(params, sg.ID) {
+			continue
+		}
+		sgs = append(sgs, s.securityGroupResponse(sg).SecurityGroup)
+	}
+
+	start, end := page(params, len(sgs))
+	return egoscale.ListSecurityGroupsResponse{Count: len(sgs), SecurityGroup: sgs[start:end]}, nil
+}
+
+// rules builds the rules out of an authorization request, one per CIDR or user security group
+func (s *Server) rules(params url.Values, sg *egoscale.SecurityGroup) []egoscale.IngressRule {
+	template := egoscale.IngressRule{
+		Description:       params.Get("description"),
+		Protocol:          params.Get("protocol"),
+		SecurityGroupID:   sg.ID,
+		SecurityGroupName: sg.Name,
+	}
+	template.StartPort, _ = strconv.Atoi(params.Get("startport"))
+	template.EndPort, _ = strconv.Atoi(params.Get("endport"))
+	template.IcmpType, _ = strconv.Atoi(params.Get("icmptype"))
+	template.IcmpCode, _ = strconv.Atoi(params.Get("icmpcode"))
+
+	rules := make([]egoscale.IngressRule, 0)
+	for _, cidr := range list(params, "cidrlist") {
+		rule := template
+		rule.RuleID = s.nextID()
+		rule.Cidr = cidr
+		rules = append(rules, rule)
+	}
+	for _, group := range indexed(params, "usersecuritygrouplist") {
+		rule := template
+		rule.RuleID = s.nextID()
+		rule.UserSecurityGroupList = []egoscale.UserSecurityGroup{{
+			Group:   group["group"],
+			Account: group["account"],
+		}}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (s *Server) authorizeSecurityGroupIngress(params url.Values) (func() (interface{}, error), error) {
+	sg, err := s.securityGroup(params, "securitygroupid", "securitygroupname")
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, error) {
+		sg.IngressRule = append(sg.IngressRule, s.rules(params, sg)...)
+		return s.securityGroupResponse(sg), nil
+	}, nil
+}
+
+func (s *Server) authorizeSecurityGroupEgress(params url.Values) (func() (interface{}, error), error) {
+	sg, err := s.securityGroup(params, "securitygroupid", "securitygroupname")
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, error) {
+		for _, rule := range s.rules(params, sg) {
+			sg.EgressRule = append(sg.EgressRule, egoscale.EgressRule(rule))
+		}
+		return s.securityGroupResponse(sg), nil
+	}, nil
+}
+
+func (s *Server) revokeSecurityGroupIngress(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sg := range s.sgs {
+		for i, rule := range sg.IngressRule {
+			if rule.RuleID == id {
+				sg, i := sg, i
+				return func() (interface{}, error) {
+					sg.IngressRule = append(sg.IngressRule[:i], sg.IngressRule[i+1:]...)
+					return success, nil
+				}, nil
+			}
+		}
+	}
+	return nil, notFound("id", id)
+}
+
+func (s *Server) revokeSecurityGroupEgress(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sg := range s.sgs {
+		for i, rule := range sg.EgressRule {
+			if rule.RuleID == id {
+				sg, i := sg, i
+				return func() (interface{}, error) {
+					sg.EgressRule = append(sg.EgressRule[:i], sg.EgressRule[i+1:]...)
+					return success, nil
+				}, nil
+			}
+		}
+	}
+	return nil, notFound("id", id)
+}
+
+// Public IP addresses
+
+func (s *Server) associateIPAddress(params url.Values) (func() (interface{}, error), error) {
+	zoneID, err := require(params, "zoneid")
+	if err != nil {
+		return nil, err
+	}
+	if zoneID != DefaultZoneID {
+		return nil, notFound("zoneid", zoneID)
+	}
+
+	return func() (interface{}, error) {
+		id := s.nextID()
+		ip := &egoscale.IPAddress{
+			ID:        id,
+			IPAddress: net.IPv4(159, 100, 240, byte(s.sequence%250+1)),
+			IsElastic: true,
+			State:     "Allocated",
+			ZoneID:    zoneID,
+			ZoneName:  DefaultZoneName,
+		}
+		s.ips[id] = ip
+		return egoscale.AssociateIPAddressResponse{IPAddress: *ip}, nil
+	}, nil
+}
+
+func (s *Server) disassociateIPAddress(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.ips[id]; !ok {
+		return nil, notFound("id", id)
+	}
+
+	return func() (interface{}, error) {
+		delete(s.ips, id)
+		return success, nil
+	}, nil
+}
+
+func (s *Server) listPublicIPAddresses(params url.Values) (interface{}, error) {
+	ips := make([]egoscale.IPAddress, 0)
+	for _, id := range sortedKeys(s.ips) {
+		ip := s.ips[id]
+		if v := params.Get("id"); v != "" && v != ip.ID {
+			continue
+		}
+		if v := params.Get("ipaddress"); v != "" && v != ip.IPAddress.String() {
+			continue
+		}
+		if v := params.Get("zoneid"); v != "" && v != ip.ZoneID {
+			continue
+		}
+		if !s.matchesTags(params, ip.ID) {
+			continue
+		}
+		address := *ip
+		address.Tags = s.resourceTags(ip.ID)
+		ips = append(ips, address)
+	}
+
+	start, end := page(params, len(ips))
+	return egoscale.ListPublicIPAddressesResponse{Count: len(ips), PublicIPAddress: ips[start:end]}, nil
+}
+
+// Tags
+
+// resourceExists tells whether the resource of the given type exists
+func (s *Server) resourceExists(resourceType, id string) bool {
+	switch resourceType {
+	case "UserVm":
+		_, ok := s.vms[id]
+		return ok
+	case "SecurityGroup":
+		_, ok := s.sgs[id]
+		return ok
+	case "PublicIpAddress":
+		_, ok := s.ips[id]
+		return ok
+	case "Volume":
+		_, ok := s.volumes[id]
+		return ok
+	case "Snapshot":
+		_, ok := s.snapshots[id]
+		return ok
+	}
+	return false
+}
+
+func (s *Server) createTags(params url.Values) (func() (interface{}, error), error) {
+	resourceType, err := require(params, "resourcetype")
+	if err != nil {
+		return nil, err
+	}
+	ids := list(params, "resourceids")
+	if len(ids) == 0 {
+		return nil, paramError("Unable to execute API command due to missing parameter resourceids")
+	}
+	for _, id := range ids {
+		if !s.resourceExists(resourceType, id) {
+			return nil, notFound("resourceids", id)
+		}
+	}
+	tags := indexed(params, "tags")
+	if len(tags) == 0 {
+		return nil, paramError("Unable to execute API command due to missing parameter tags")
+	}
+
+	return func() (interface{}, error) {
+		for _, id := range ids {
+			for _, tag := range tags {
+				s.removeTag(id, tag["key"])
+				s.tags = append(s.tags, egoscale.ResourceTag{
+					Key:          tag["key"],
+					Value:        tag["value"],
+					ResourceID:   id,
+					ResourceType: resourceType,
+					Customer:     params.Get("customer"),
+				})
+			}
+		}
+		return success, nil
+	}, nil
+}
+
+func (s *Server) deleteTags(params url.Values) (func() (interface{}, error), error) {
+	if _, err := require(params, "resourcetype"); err != nil {
+		return nil, err
+	}
+	ids := list(params, "resourceids")
+	tags := indexed(params, "tags")
+
+	return func() (interface{}, error) {
+		for _, id := range ids {
+			if len(tags) == 0 {
+				s.removeTag(id, "")
+			}
+			for _, tag := range tags {
+				s.removeTag(id, tag["key"])
+			}
+		}
+		return success, nil
+	}, nil
+}
+
+// removeTag removes the tag of the resource, all of them if key is empty
+func (s *Server) removeTag(resourceID, key string) {
+	tags := s.tags[:0]
+	for _, t := range s.tags {
+		if t.ResourceID != resourceID || (key != "" && t.Key != key) {
+			tags = append(tags, t)
+		}
+	}
+	s.tags = tags
+}
+
+func (s *Server) listTags(params url.Values) (interface{}, error) {
+	tags := make([]egoscale.ResourceTag, 0)
+	for _, t := range s.tags {
+		if v := params.Get("resourceid"); v != "" && v != t.ResourceID {
+			continue
+		}
+		if v := params.Get("resourcetype"); v != "" && v != t.ResourceType {
+			continue
+		}
+		if v := params.Get("key"); v != "" && v != t.Key {
+			continue
+		}
+		if v := params.Get("value"); v != "" && v != t.Value {
+			continue
+		}
+		tags = append(tags, t)
+	}
+
+	start, end := page(params, len(tags))
+	return egoscale.ListTagsResponse{Count: len(tags), Tag: tags[start:end]}, nil
+}
+
+// Volumes
+
+func (s *Server) listVolumes(params url.Values) (interface{}, error) {
+	volumes := make([]egoscale.Volume, 0)
+	for _, id := range sortedKeys(s.volumes) {
+		volume := s.volumes[id]
+		if v := params.Get("id"); v != "" && v != volume.ID {
+			continue
+		}
+		if v := params.Get("name"); v != "" && v != volume.Name {
+			continue
+		}
+		if v := params.Get("type"); v != "" && v != volume.Type {
+			continue
+		}
+		if v := params.Get("virtualmachineid"); v != "" && v != volume.VirtualMachineID {
+			continue
+		}
+		if !s.matchesTags(params, volume.ID) {
+			continue
+		}
+		vol := *volume
+		vol.Tags = s.resourceTags(volume.ID)
+		volumes = append(volumes, vol)
+	}
+
+	start, end := page(params, len(volumes))
+	return egoscale.ListVolumesResponse{Count: len(volumes), Volume: volumes[start:end]}, nil
+}
+
+func (s *Server) resizeVolume(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+	volume, ok := s.volumes[id]
+	if !ok {
+		return nil, notFound("id", id)
+	}
+	size, err := strconv.ParseUint(params.Get("size"), 10, 64)
+	if err != nil || size == 0 {
+		return nil, paramError("Invalid size %q", params.Get("size"))
+	}
+	if size<<30 < volume.Size && params.Get("shrinkok") != "true" {
+		return nil, paramError("Shrinking the volume %s requires shrinkok", id)
+	}
+
+	return func() (interface{}, error) {
+		volume.Size = size << 30
+		return egoscale.ResizeVolumeResponse{Volume: *volume}, nil
+	}, nil
+}
+
+// Snapshots
+
+func (s *Server) createSnapshot(params url.Values) (func() (interface{}, error), error) {
+	volumeID, err := require(params, "volumeid")
+	if err != nil {
+		return nil, err
+	}
+	volume, ok := s.volumes[volumeID]
+	if !ok {
+		return nil, notFound("volumeid", volumeID)
+	}
+
+	return func() (interface{}, error) {
+		id := s.nextID()
+		snapshot := &egoscale.Snapshot{
+			ID:           id,
+			Name:         fmt.Sprintf("%s_%s", volume.Name, id),
+			State:        "BackedUp",
+			SnapshotType: "MANUAL",
+			Revertable:   true,
+			Size:         int64(volume.Size),
+			PhysicalSize: int64(volume.Size),
+			VolumeID:     volume.ID,
+			VolumeName:   volume.Name,
+			VolumeType:   volume.Type,
+			ZoneID:       volume.ZoneID,
+			Tags:         []egoscale.ResourceTag{},
+		}
+		s.snapshots[id] = snapshot
+		return egoscale.CreateSnapshotResponse{Snapshot: *snapshot}, nil
+	}, nil
+}
+
+func (s *Server) listSnapshots(params url.Values) (interface{}, error) {
+	snapshots := make([]egoscale.Snapshot, 0)
+	for _, id := range sortedKeys(s.snapshots) {
+		snapshot := s.snapshots[id]
+		if v := params.Get("id"); v != "" && v != snapshot.ID {
+			continue
+		}
+		if v := params.Get("volumeid"); v != "" && v != snapshot.VolumeID {
+			continue
+		}
+		if !s.matchesTags(params, snapshot.ID) {
+			continue
+		}
+		snap := *snapshot
+		snap.Tags = s.resourceTags(snapshot.ID)
+		snapshots = append(snapshots, snap)
+	}
+
+	start, end := page(params, len(snapshots))
+	return egoscale.ListSnapshotsResponse{Count: len(snapshots), Snapshot: snapshots[start:end]}, nil
+}
+
+func (s *Server) deleteSnapshot(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.snapshots[id]; !ok {
+		return nil, notFound("id", id)
+	}
+
+	return func() (interface{}, error) {
+		delete(s.snapshots, id)
+		return success, nil
+	}, nil
+}
+
+func (s *Server) revertSnapshot(params url.Values) (func() (interface{}, error), error) {
+	id, err := require(params, "id")
+	if err != nil {
+		return nil, err
+	}
+	snapshot, ok := s.snapshots[id]
+	if !ok {
+		return nil, notFound("id", id)
+	}
+
+	return func() (interface{}, error) {
+		if volume, ok := s.volumes[snapshot.VolumeID]; ok {
+			volume.Size = uint64(snapshot.Size)
+		}
+		return success, nil
+	}, nil
+}
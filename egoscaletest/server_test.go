@@ -0,0 +1,178 @@
+package egoscaletest
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/exoscale/egoscale"
+)
+
+func TestServerVirtualMachine(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	cs := s.NewClient()
+
+	resp, err := cs.Request(&egoscale.DeployVirtualMachine{
+		Name:              "test",
+		ServiceOfferingID: "71004023-bb72-4a97-b1e9-bc66dfce9470",
+		TemplateID:        "78c2cbe6-8e11-4335-a9d1-6d4e8a46b2b2",
+		ZoneID:            DefaultZoneID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := resp.(*egoscale.DeployVirtualMachineResponse).VirtualMachine
+	if vm.State != "Running" || len(vm.SecurityGroup) != 1 || vm.SecurityGroup[0].Name != "default" {
+		t.Errorf("bad virtual machine, got %#v", vm)
+	}
+
+	if _, err := cs.Request(&egoscale.StopVirtualMachine{ID: vm.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Request(&egoscale.StopVirtualMachine{ID: vm.ID}); err == nil {
+		t.Errorf("stopping a stopped virtual machine should fail")
+	}
+
+	err = cs.BooleanRequest(&egoscale.CreateTags{
+		ResourceIDs:  []string{vm.ID},
+		ResourceType: "UserVm",
+		Tags:         []egoscale.ResourceTag{{Key: "env", Value: "test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = cs.Request(&egoscale.ListVirtualMachines{
+		State: "Stopped",
+		Tags:  []egoscale.ResourceTag{{Key: "env", Value: "test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vms := resp.(*egoscale.ListVirtualMachinesResponse)
+	if vms.Count != 1 || vms.VirtualMachine[0].ID != vm.ID || len(vms.VirtualMachine[0].Tags) != 1 {
+		t.Errorf("the tagged virtual machine was expected, got %#v", vms)
+	}
+
+	resp, err = cs.Request(&egoscale.ListVolumes{VirtualMachineID: vm.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volumes := resp.(*egoscale.ListVolumesResponse); volumes.Count != 1 || volumes.Volume[0].Type != "ROOT" {
+		t.Errorf("the root volume was expected, got %#v", volumes)
+	}
+
+	expunge := true
+	if _, err := cs.Request(&egoscale.DestroyVirtualMachine{ID: vm.ID, Expunge: &expunge}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cs.Get(&egoscale.VirtualMachine{ID: vm.ID})
+	if !errors.Is(err, egoscale.ErrNotFound) {
+		t.Errorf("ErrNotFound was expected, got %v", err)
+	}
+}
+
+func TestServerSecurityGroup(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	cs := s.NewClient()
+
+	resp, err := cs.Request(&egoscale.CreateSecurityGroup{Name: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := resp.(*egoscale.CreateSecurityGroupResponse).SecurityGroup
+
+	_, err = cs.Request(&egoscale.AuthorizeSecurityGroupIngress{
+		SecurityGroupName: "web",
+		Protocol:          "tcp",
+		StartPort:         80,
+		EndPort:           80,
+		CidrList:          []string{"0.0.0.0/0", "::/0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group := &egoscale.SecurityGroup{ID: sg.ID}
+	if err := cs.Get(group); err != nil {
+		t.Fatal(err)
+	}
+	if len(group.IngressRule) != 2 || group.IngressRule[0].StartPort != 80 {
+		t.Errorf("two ingress rules were expected, got %#v", group.IngressRule)
+	}
+
+	if err := cs.BooleanRequest(&egoscale.DeleteSecurityGroup{Name: "web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cs.Request(&egoscale.CreateSecurityGroup{Name: "default"})
+	if !errors.Is(err, egoscale.ErrInvalidParameter) {
+		t.Errorf("ErrInvalidParameter was expected, got %v", err)
+	}
+}
+
+func TestServerSignature(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+
+	cs := egoscale.NewClient(s.URL, "KEY", "WRONG")
+	_, err := cs.Request(&egoscale.ListZones{})
+	if !errors.Is(err, egoscale.ErrUnauthorized) {
+		t.Errorf("ErrUnauthorized was expected, got %v", err)
+	}
+}
+
+func TestServerPendingPolls(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	s.PendingPolls = 2
+
+	polls := 0
+	cs := s.NewClient()
+	cs.Interceptors = []egoscale.Interceptor{{
+		BeforeSign: func(ctx context.Context, command string, params url.Values) error {
+			if command == "queryAsyncJobResult" {
+				polls++
+			}
+			return nil
+		},
+	}}
+
+	resp, err := cs.Request(&egoscale.AssociateIPAddress{ZoneID: DefaultZoneID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := resp.(*egoscale.AssociateIPAddressResponse).IPAddress; ip.IPAddress == nil {
+		t.Errorf("an IP address was expected, got %#v", ip)
+	}
+
+	if polls != 3 {
+		t.Errorf("the job should have been polled 3 times, got %d", polls)
+	}
+}
+
+func TestServerInvalidParameters(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+
+	cs := s.NewClient()
+	cs.Interceptors = []egoscale.Interceptor{{
+		BeforeSign: func(ctx context.Context, command string, params url.Values) error {
+			params.Set("rootdisksize", "big")
+			return nil
+		},
+	}}
+
+	_, err := cs.Request(&egoscale.DeployVirtualMachine{
+		ServiceOfferingID: "71004023-bb72-4a97-b1e9-bc66dfce9470",
+		TemplateID:        "78c2cbe6-8e11-4335-a9d1-6d4e8a46b2b2",
+		ZoneID:            DefaultZoneID,
+	})
+	if !errors.Is(err, egoscale.ErrInvalidParameter) {
+		t.Errorf("ErrInvalidParameter was expected, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrNilCommand is returned when a nil command is given to the client
+var ErrNilCommand = errors.New("egoscale: nil command")
+
+// ErrInvalidSignature is returned by VerifySignature when the signature is missing or doesn't match
+var ErrInvalidSignature = errors.New("egoscale: invalid signature")
+
+// ErrSignatureExpired is returned by VerifySignature when a version 3 signature has expired
+var ErrSignatureExpired = errors.New("egoscale: signature expired")
+
+// The sentinel errors below are meant to be used with errors.Is
+//
+//	if errors.Is(err, egoscale.ErrNotFound) {
+//		// ...
+//	}
+//
+// The ErrorResponse coming from CloudStack can be obtained via errors.As
+var (
+	// ErrNotFound represents a resource that doesn't exist
+	ErrNotFound = errors.New("egoscale: not found")
+	// ErrMultipleResults represents a query that was expected to match only one resource
+	ErrMultipleResults = errors.New("egoscale: more than one result")
+	// ErrUnauthorized represents a failed authentication (401)
+	ErrUnauthorized = errors.New("egoscale: unauthorized")
+	// ErrMethodNotAllowed represents a forbidden method (405)
+	ErrMethodNotAllowed = errors.New("egoscale: method not allowed")
+	// ErrUnsupportedAction represents an unsupported action (422)
+	ErrUnsupportedAction = errors.New("egoscale: unsupported action")
+	// ErrLimitExceeded represents too many requests (429)
+	ErrLimitExceeded = errors.New("egoscale: API limit exceeded")
+	// ErrInvalidParameter represents a malformed or invalid parameter (430, 431)
+	ErrInvalidParameter = errors.New("egoscale: invalid parameter")
+	// ErrInternal represents a server error (530)
+	ErrInternal = errors.New("egoscale: internal error")
+	// ErrAccount represents an account error (531)
+	ErrAccount = errors.New("egoscale: account error")
+	// ErrResourceLimit represents an account that reached its resource limits (532)
+	ErrResourceLimit = errors.New("egoscale: account resource limit exceeded")
+	// ErrInsufficientCapacity represents a lack of capacity (533)
+	ErrInsufficientCapacity = errors.New("egoscale: insufficient capacity")
+	// ErrResourceUnavailable represents an unavailable resource (534)
+	ErrResourceUnavailable = errors.New("egoscale: resource unavailable")
+	// ErrResourceAllocation represents a failed allocation (535)
+	ErrResourceAllocation = errors.New("egoscale: resource allocation error")
+	// ErrResourceInUse represents a busy resource (536)
+	ErrResourceInUse = errors.New("egoscale: resource in use")
+	// ErrNetworkRuleConflict represents a conflicting network rule (537)
+	ErrNetworkRuleConflict = errors.New("egoscale: network rule conflict")
+)
+
+// errorCodes maps the CloudStack error codes to the sentinel errors
+var errorCodes = map[ErrorCode]error{
+	Unauthorized:              ErrUnauthorized,
+	MethodNotAllowed:          ErrMethodNotAllowed,
+	UnsupportedActionError:    ErrUnsupportedAction,
+	APILimitExceeded:          ErrLimitExceeded,
+	MalformedParameterError:   ErrInvalidParameter,
+	ParamError:                ErrInvalidParameter,
+	InternalError:             ErrInternal,
+	AccountError:              ErrAccount,
+	AccountResourceLimitError: ErrResourceLimit,
+	InsufficientCapacityError: ErrInsufficientCapacity,
+	ResourceUnavailableError:  ErrResourceUnavailable,
+	ResourceAllocationError:   ErrResourceAllocation,
+	ResourceInUseError:        ErrResourceInUse,
+	NetworkRuleConflictError:  ErrNetworkRuleConflict,
+}
+
+// Is tells whether the ErrorResponse matches the given sentinel error
+func (e *ErrorResponse) Is(target error) bool {
+	if e.kind != nil {
+		return e.kind == target
+	}
+
+	if target == ErrNotFound {
+		// CloudStack reports an unknown ID as an invalid parameter
+		return e.ErrorCode == ParamError && strings.Contains(e.ErrorText, "entity does not exist")
+	}
+
+	err, ok := errorCodes[e.ErrorCode]
+	return ok && err == target
+}
+
+// notFoundError builds the error of a resource that cannot be found
+func notFoundError(format string, args ...interface{}) error {
+	return &ErrorResponse{
+		ErrorCode: ParamError,
+		ErrorText: fmt.Sprintf(format, args...),
+		kind:      ErrNotFound,
+	}
+}
+
+// multipleResultsError builds the error of a query matching too many resources
+func multipleResultsError(format string, args ...interface{}) error {
+	return &kindError{
+		msg:  fmt.Sprintf(format, args...),
+		kind: ErrMultipleResults,
+	}
+}
+
+// kindError is an error message attached to a sentinel error
+type kindError struct {
+	msg  string
+	kind error
+}
+
+// Error formats the error
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the sentinel error
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// UnsupportedCommandError is returned when a command is neither sync nor async
+type UnsupportedCommandError struct {
+	Command string
+}
+
+// Error formats the error
+func (e *UnsupportedCommandError) Error() string {
+	return fmt.Sprintf("The command %s is not a proper Sync or Async command", e.Command)
+}
+
+// UnexpectedResponseError is returned when the response doesn't match what the command expects
+type UnexpectedResponseError struct {
+	Command  string
+	Response interface{}
+}
+
+// Error formats the error
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("The command %s didn't get the expected response, got %T", e.Command, e.Response)
+}
+
+// ErrorClass represents how an error ought to be handled by a retry logic
+type ErrorClass int
+
+const (
+	// Terminal represents an error that will happen again if retried
+	Terminal ErrorClass = iota
+	// Retryable represents a transient error, it may succeed later on
+	Retryable
+)
+
+// String returns the name of the class
+func (c ErrorClass) String() string {
+	if c == Retryable {
+		return "retryable"
+	}
+	return "terminal"
+}
+
+// retryableCSErrorCodes are the CloudStack exceptions that are transient
+var retryableCSErrorCodes = map[CSErrorCode]bool{
+	AgentUnavailableException:            true,
+	ConcurrentOperationException:         true,
+	InsufficientAddressCapacityException: true,
+	InsufficientCapacityException:        true,
+	InsufficientNetworkCapacityException: true,
+	InsufficientServerCapacityException:  true,
+	InsufficientStorageCapacityException: true,
+	ResourceUnavailableException:         true,
+	StorageUnavailableException:          true,
+	RequestLimitException:                true,
+}
+
+// terminalCSErrorCodes are the CloudStack exceptions that won't go away if retried
+var terminalCSErrorCodes = map[CSErrorCode]bool{
+	AccountLimitException:               true,
+	CloudAuthenticationException:        true,
+	ConflictingNetworkSettingsException: true,
+	InvalidParameterValueException:      true,
+	NetworkRuleConflictException:        true,
+	PermissionDeniedException:           true,
+	UnsupportedServiceException:         true,
+}
+
+// retryableErrorCodes are the CloudStack error codes that are transient
+var retryableErrorCodes = map[ErrorCode]bool{
+	APILimitExceeded:          true,
+	InternalError:             true,
+	InsufficientCapacityError: true,
+	ResourceUnavailableError:  true,
+}
+
+// ClassifyError tells whether the given error is worth retrying or not
+//
+// CloudStack errors are classified using the CsErrorCode first, which is the
+// most precise information, and then the ErrorCode. Network timeouts,
+// including the http.Client.Timeout of an attempt, connection resets and 502,
+// 503 or 504 HTTP errors are retryable while a cancelled or expired context
+// is not.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return Terminal
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return Terminal
+	}
+	if isAttemptTimeout(err) {
+		return Retryable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Terminal
+	}
+
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		if e.kind != nil {
+			return Terminal
+		}
+		if retryableCSErrorCodes[e.CsErrorCode] {
+			return Retryable
+		}
+		if terminalCSErrorCodes[e.CsErrorCode] {
+			return Terminal
+		}
+		if retryableErrorCodes[e.ErrorCode] {
+			return Retryable
+		}
+		return Terminal
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return Retryable
+	}
+
+	var httpErr *HTTPStatusError
+	if errors.As(err, &httpErr) {
+		if httpErr.Temporary() {
+			return Retryable
+		}
+		return Terminal
+	}
+
+	return Terminal
+}
+
+// isAttemptTimeout tells whether the error is the timeout of a single attempt, e.g. http.Client.Timeout
+//
+// The expired context itself, even wrapped by an url.Error, is not one.
+func isAttemptTimeout(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err == context.DeadlineExceeded {
+			return false
+		}
+		if _, ok := err.(*url.Error); ok {
+			// its Timeout is the one of the wrapped error
+			continue
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable tells whether the given error is a transient one
+func IsRetryable(err error) bool {
+	return ClassifyError(err) == Retryable
+}
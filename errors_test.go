@@ -0,0 +1,205 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+type dummyCommand struct{}
+
+func (*dummyCommand) APIName() string {
+	return "dummy"
+}
+
+func TestRequestUnsupportedCommand(t *testing.T) {
+	cs := NewClient("http://127.0.0.1", "KEY", "SECRET")
+
+	_, err := cs.Request(&dummyCommand{})
+	e, ok := err.(*UnsupportedCommandError)
+	if !ok {
+		t.Fatalf("an UnsupportedCommandError was expected, got %v", err)
+	}
+	if e.Command != "dummy" {
+		t.Errorf("bad command, got %q", e.Command)
+	}
+
+	if err := cs.BooleanRequest(&dummyCommand{}); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestRequestNilCommand(t *testing.T) {
+	cs := NewClient("http://127.0.0.1", "KEY", "SECRET")
+
+	if _, err := cs.Request(nil); err != ErrNilCommand {
+		t.Errorf("ErrNilCommand was expected, got %v", err)
+	}
+
+	if _, err := cs.Request((*ListZones)(nil)); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestBooleanRequestUnexpectedResponse(t *testing.T) {
+	ts := newServer(response{200, `{"listzonesresponse": {"count": 0, "zone": []}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	err := cs.BooleanRequest(&ListZones{})
+	e, ok := err.(*UnexpectedResponseError)
+	if !ok {
+		t.Fatalf("an UnexpectedResponseError was expected, got %v", err)
+	}
+	if _, ok := e.Response.(*ListZonesResponse); !ok || e.Command != "listZones" {
+		t.Errorf("bad UnexpectedResponseError, got %#v", e)
+	}
+}
+
+func TestEachUnexpectedResponse(t *testing.T) {
+	var err error
+	(&ListZones{}).each(&ListVolumesResponse{}, func(item interface{}, e error) bool {
+		if item != nil {
+			t.Errorf("no items were expected, got %v", item)
+		}
+		err = e
+		return false
+	})
+
+	if _, ok := err.(*UnexpectedResponseError); !ok {
+		t.Errorf("an UnexpectedResponseError was expected, got %v", err)
+	}
+}
+
+func TestPrepareValuesNotAStruct(t *testing.T) {
+	params := url.Values{}
+	if err := prepareValues("", &params, "foo", nil); err == nil {
+		t.Errorf("a string cannot be serialized")
+	}
+	if err := prepareValues("", &params, nil, nil); err == nil {
+		t.Errorf("nil cannot be serialized")
+	}
+
+	profile := struct {
+		IDs []int `json:"ids"`
+	}{
+		IDs: []int{1, 2},
+	}
+	if err := prepareValues("", &params, profile, nil); err == nil {
+		t.Errorf("a list of int cannot be serialized")
+	}
+}
+
+func TestEmptyArrayResponse(t *testing.T) {
+	ts := newServer(response{200, `[]`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	if _, err := cs.Request(&ListZones{}); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestErrorResponseIs(t *testing.T) {
+	err := error(&ErrorResponse{ErrorCode: APILimitExceeded, ErrorText: "slow down"})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ErrLimitExceeded was expected, got %v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("ErrNotFound wasn't expected")
+	}
+
+	err = fmt.Errorf("wrapped: %w", &ErrorResponse{ErrorCode: Unauthorized})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("ErrUnauthorized was expected, got %v", err)
+	}
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.ErrorCode != Unauthorized {
+		t.Errorf("an ErrorResponse was expected, got %v", err)
+	}
+
+	err = &ErrorResponse{ErrorCode: ParamError, ErrorText: "Unable to execute API command due to invalid value. Invalid parameter id value=42 due to incorrect long value format, or entity does not exist or due to incorrect parameter annotation for the field in api cmd class."}
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("ErrNotFound and ErrInvalidParameter were expected, got %v", err)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	ts := newServer(
+		response{200, `{"listvirtualmachinesresponse": {"count": 0, "virtualmachine": []}}`},
+		response{200, `{"listvirtualmachinesresponse": {"count": 2, "virtualmachine": [{"id": "1"}, {"id": "2"}]}}`},
+	)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	err := cs.Get(&VirtualMachine{Name: "foo"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("ErrNotFound was expected, got %v", err)
+	}
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.ErrorCode != ParamError {
+		t.Errorf("a ParamError was expected, got %v", err)
+	}
+
+	err = cs.Get(&VirtualMachine{Name: "foo"})
+	if !errors.Is(err, ErrMultipleResults) {
+		t.Errorf("ErrMultipleResults was expected, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "More than one VirtualMachine") {
+		t.Errorf("bad error message, got %q", err)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// clientTimeoutError looks like the error of an http.Client.Timeout
+type clientTimeoutError struct{ timeoutError }
+
+func (clientTimeoutError) Is(err error) bool { return err == context.DeadlineExceeded }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{nil, Terminal},
+		{errors.New("boom"), Terminal},
+		{context.Canceled, Terminal},
+		{context.DeadlineExceeded, Terminal},
+		{io.ErrUnexpectedEOF, Retryable},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1", Err: syscall.ECONNRESET}, Retryable},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1", Err: timeoutError{}}, Retryable},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1", Err: clientTimeoutError{}}, Retryable},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1", Err: context.DeadlineExceeded}, Terminal},
+		{&ErrorResponse{ErrorCode: APILimitExceeded}, Retryable},
+		{&ErrorResponse{ErrorCode: InternalError}, Retryable},
+		{&ErrorResponse{ErrorCode: InternalError, CsErrorCode: InvalidParameterValueException}, Terminal},
+		{&ErrorResponse{ErrorCode: ParamError, CsErrorCode: ConcurrentOperationException}, Retryable},
+		{&ErrorResponse{ErrorCode: ParamError, CsErrorCode: InvalidParameterValueException}, Terminal},
+		{&ErrorResponse{ErrorCode: Unauthorized}, Terminal},
+		{&AsyncJobError{Command: "deployVirtualMachine", JobID: "1", Err: context.DeadlineExceeded}, Terminal},
+		{&AsyncJobError{Command: "deployVirtualMachine", JobID: "1", Err: &ErrorResponse{CsErrorCode: InsufficientServerCapacityException}}, Retryable},
+		{notFoundError("VirtualMachine not found"), Terminal},
+	}
+
+	for _, test := range tests {
+		if class := ClassifyError(test.err); class != test.class {
+			t.Errorf("%v should be %s, got %s", test.err, test.class, class)
+		}
+	}
+
+	if !IsRetryable(&ErrorResponse{ErrorCode: ResourceUnavailableError}) {
+		t.Errorf("a ResourceUnavailableError should be retryable")
+	}
+}
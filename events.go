@@ -1,20 +1,22 @@
 package egoscale
 
+import "net/url"
+
 // Event represents an event in the system
 type Event struct {
-	ID          string `json:"id"`
-	Account     string `json:"account"`
-	Created     string `json:"created"`
-	Description string `json:"description,omitempty"`
-	Domain      string `json:"domain,omitempty"`
-	DomainID    string `json:"domainid,omitempty"`
-	Level       string `json:"level"` // INFO, WARN, ERROR
-	ParentID    string `json:"parentid,omitempty"`
-	Project     string `json:"project,omitempty"`
-	ProjectID   string `json:"projectid,omitempty"`
-	State       string `json:"state,omitempty"`
-	Type        string `json:"type"`
-	UserName    string `json:"username,omitempty"`
+	ID          string     `json:"id"`
+	Account     string     `json:"account"`
+	Created     *Timestamp `json:"created,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Domain      string     `json:"domain,omitempty"`
+	DomainID    string     `json:"domainid,omitempty"`
+	Level       string     `json:"level"` // INFO, WARN, ERROR
+	ParentID    string     `json:"parentid,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	ProjectID   string     `json:"projectid,omitempty"`
+	State       string     `json:"state,omitempty"`
+	Type        string     `json:"type"`
+	UserName    string     `json:"username,omitempty"`
 }
 
 // EventType represent a type of event
@@ -26,21 +28,21 @@ type EventType struct {
 //
 // CloudStack API: http://cloudstack.apache.org/api/apidocs-4.10/apis/listEvents.html
 type ListEvents struct {
-	Account     string `json:"account,omitempty"`
-	DomainID    string `json:"domainid,omitempty"`
-	Duration    int    `json:"duration,omitempty"`
-	EndDate     string `json:"enddate,omitempty"`
-	EntryTime   int    `json:"entrytime,omitempty"`
-	ID          string `json:"id,omitempty"`
-	IsRecursive *bool  `json:"isrecursive,omitempty"`
-	Keyword     string `json:"keyword,omitempty"`
-	Level       string `json:"level,omitempty"` // INFO, WARN, ERROR
-	ListAll     *bool  `json:"listall,omitempty"`
-	Page        int    `json:"page,omitempty"`
-	PageSize    int    `json:"pagesize,omitempty"`
-	ProjectID   string `json:"projectid,omitempty"`
-	StartDate   string `json:"startdate,omitempty"`
-	Type        string `json:"type,omitempty"`
+	Account     string     `json:"account,omitempty"`
+	DomainID    string     `json:"domainid,omitempty"`
+	Duration    int        `json:"duration,omitempty"`
+	EndDate     *Timestamp `json:"enddate,omitempty"`
+	EntryTime   int        `json:"entrytime,omitempty"`
+	ID          string     `json:"id,omitempty"`
+	IsRecursive *bool      `json:"isrecursive,omitempty"`
+	Keyword     string     `json:"keyword,omitempty"`
+	Level       string     `json:"level,omitempty"` // INFO, WARN, ERROR
+	ListAll     *bool      `json:"listall,omitempty"`
+	Page        int        `json:"page,omitempty"`
+	PageSize    int        `json:"pagesize,omitempty"`
+	ProjectID   string     `json:"projectid,omitempty"`
+	StartDate   *Timestamp `json:"startdate,omitempty"`
+	Type        string     `json:"type,omitempty"`
 }
 
 // APIName returns the CloudStack API command name
@@ -52,6 +54,20 @@ func (*ListEvents) response() interface{} {
 	return new(ListEventsResponse)
 }
 
+func (req *ListEvents) onBeforeSend(params *url.Values) error {
+	// listEvents only reads the dates without a time zone
+	if req.StartDate != nil && !req.StartDate.IsZero() {
+		params.Set("startdate", req.StartDate.Format(eventDateFormat))
+	}
+	if req.EndDate != nil && !req.EndDate.IsZero() {
+		params.Set("enddate", req.EndDate.Format(eventDateFormat))
+	}
+	return nil
+}
+
+// eventDateFormat is the layout of the dates of listEvents
+const eventDateFormat = "2006-01-02 15:04:05"
+
 // ListEventsResponse represents a response of a list query
 type ListEventsResponse struct {
 	Count int     `json:"count"`
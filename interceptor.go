@@ -0,0 +1,76 @@
+package egoscale
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Interceptor represents a set of hooks called around every API call
+//
+// The interceptors registered on the Client are called in order. Any of the
+// hooks may be left nil. A non-nil error returned by a hook aborts the call
+// and is returned to the caller.
+//
+// For the DNS API, the command is the HTTP method followed by the URI, e.g.
+// "GET /v1/domains/example.com", and BeforeSign is never called as there is
+// nothing to sign.
+type Interceptor struct {
+	// BeforeSign is called with the parameters before they get signed
+	BeforeSign func(ctx context.Context, command string, params url.Values) error
+	// AfterSign is called with the HTTP request right before it is sent
+	AfterSign func(ctx context.Context, command string, req *http.Request) error
+	// AfterResponse is called with the HTTP response or the transport error
+	AfterResponse func(ctx context.Context, command string, resp *http.Response, err error) error
+}
+
+// beforeSign runs the BeforeSign hooks
+func (exo *Client) beforeSign(ctx context.Context, command string, params url.Values) error {
+	for _, i := range exo.Interceptors {
+		if i.BeforeSign == nil {
+			continue
+		}
+		if err := i.BeforeSign(ctx, command, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterSign runs the AfterSign hooks
+func (exo *Client) afterSign(ctx context.Context, command string, req *http.Request) error {
+	for _, i := range exo.Interceptors {
+		if i.AfterSign == nil {
+			continue
+		}
+		if err := i.AfterSign(ctx, command, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends the request and runs the AfterResponse hooks
+//
+// The response body is closed if any hook fails.
+func (exo *Client) do(ctx context.Context, command string, req *http.Request) (*http.Response, error) {
+	resp, err := exo.HTTPClient.Do(req)
+
+	for _, i := range exo.Interceptors {
+		if i.AfterResponse == nil {
+			continue
+		}
+		if e := i.AfterResponse(ctx, command, resp, err); e != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, e
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,106 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "42" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"listzonesresponse": {"errorcode": 431, "errortext": "X-Trace header is missing"}}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"listzonesresponse": {"count": 0, "zone": []}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	calls := make([]string, 0)
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Interceptors = []Interceptor{{
+		BeforeSign: func(ctx context.Context, command string, params url.Values) error {
+			calls = append(calls, "before:"+command)
+			if params.Get("apikey") != "KEY" {
+				t.Errorf("apikey was expected in the params, got %v", params)
+			}
+			return nil
+		},
+		AfterSign: func(ctx context.Context, command string, req *http.Request) error {
+			calls = append(calls, "after:"+command)
+			req.Header.Set("X-Trace", "42")
+			return nil
+		},
+	}, {
+		AfterResponse: func(ctx context.Context, command string, resp *http.Response, err error) error {
+			calls = append(calls, "response:"+command)
+			if err != nil {
+				t.Errorf("no errors were expected, got %v", err)
+			}
+			return nil
+		},
+	}}
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "before:listZones after:listZones response:listZones"
+	if strings.Join(calls, " ") != expected {
+		t.Errorf("bad interceptors order, got %v", calls)
+	}
+}
+
+func TestInterceptorsAbort(t *testing.T) {
+	ts := newServer()
+	defer ts.Close()
+
+	boom := errors.New("boom")
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Interceptors = []Interceptor{{
+		AfterResponse: func(ctx context.Context, command string, resp *http.Response, err error) error {
+			return boom
+		},
+	}}
+
+	if _, err := cs.Request(&ListZones{}); err != boom {
+		t.Errorf("the interceptor error was expected, got %v", err)
+	}
+}
+
+func TestInterceptorsDNS(t *testing.T) {
+	ts := newServer(response{200, `{"domain": {"id": 1, "name": "example.com"}}`})
+	defer ts.Close()
+
+	var command string
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Interceptors = []Interceptor{{
+		BeforeSign: func(ctx context.Context, c string, params url.Values) error {
+			t.Errorf("BeforeSign is not expected on the DNS API")
+			return nil
+		},
+		AfterSign: func(ctx context.Context, c string, req *http.Request) error {
+			command = c
+			if req.Header.Get("X-DNS-TOKEN") != "KEY:SECRET" {
+				t.Errorf("X-DNS-TOKEN header is missing")
+			}
+			return nil
+		},
+	}}
+
+	if _, err := cs.GetDomain("example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if command != "GET /v1/domains/example.com" {
+		t.Errorf("bad DNS command, got %q", command)
+	}
+}
@@ -16,12 +16,9 @@ func (ssh *SSHKeyPair) Get(ctx context.Context, client *Client) error {
 
 	count := len(sshs)
 	if count == 0 {
-		return &ErrorResponse{
-			ErrorCode: ParamError,
-			ErrorText: fmt.Sprintf("SSHKeyPair not found"),
-		}
+		return notFoundError("SSHKeyPair not found")
 	} else if count > 1 {
-		return fmt.Errorf("More than one SSHKeyPair was found")
+		return multipleResultsError("More than one SSHKeyPair was found")
 	}
 
 	return copier.Copy(ssh, sshs[0])
@@ -90,8 +87,13 @@ func (*ListSSHKeyPairs) response() interface{} {
 	return new(ListSSHKeyPairsResponse)
 }
 
-func (*ListSSHKeyPairs) each(resp interface{}, callback IterateItemFunc) {
-	sshs := resp.(*ListSSHKeyPairsResponse)
+func (ls *ListSSHKeyPairs) each(resp interface{}, callback IterateItemFunc) {
+	sshs, ok := resp.(*ListSSHKeyPairsResponse)
+	if !ok {
+		callback(nil, &UnexpectedResponseError{Command: ls.APIName(), Response: resp})
+		return
+	}
+
 	for i := range sshs.SSHKeyPair {
 		if !callback(sshs.SSHKeyPair[i], nil) {
 			break
@@ -0,0 +1,207 @@
+package egoscale
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Level represents the severity of a log entry
+type Level int
+
+const (
+	// LevelDebug represents the details of every API call
+	LevelDebug Level = iota
+	// LevelInfo represents something worth knowing, e.g. a command not sent in DryRun mode
+	LevelInfo
+	// LevelWarn represents something unexpected, e.g. a skipped field
+	LevelWarn
+	// LevelError represents a failure
+	LevelError
+)
+
+// String formats the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Field represents a key and its value in a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger represents a structured logger
+//
+// The client redacts the secrets, e.g. the apikey, the signature or the
+// passwords, found in the url.Values and http.Header fields before giving
+// them to the Logger.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// StdLogger writes the entries from the given Level using the standard log package
+//
+//	[WARN] field skipped type=DeployVirtualMachine field=Foo reason=no json label found
+type StdLogger struct {
+	// Level is the minimum level of the entries written
+	Level Level
+	// Logger is where the entries are written, the standard logger if nil
+	Logger *log.Logger
+}
+
+// Log writes the entry
+func (l *StdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < l.Level {
+		return
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "[%s] %s", level, msg)
+	for _, f := range fields {
+		fmt.Fprintf(&buf, " %s=%s", f.Key, formatValue(f.Value))
+	}
+
+	if l.Logger != nil {
+		l.Logger.Print(buf.String())
+		return
+	}
+	log.Print(buf.String())
+}
+
+// NopLogger discards every entry
+type NopLogger struct{}
+
+// Log does nothing
+func (NopLogger) Log(level Level, msg string, fields ...Field) {}
+
+// DefaultLogger is the Logger of the new clients, it writes the entries from LevelInfo
+var DefaultLogger Logger = &StdLogger{Level: LevelInfo}
+
+// Redacted replaces the secrets in the logs
+const Redacted = "[REDACTED]"
+
+// redactedKeys are the parameters and headers never logged
+var redactedKeys = []string{"apikey", "signature", "x-dns-token", "userdata"}
+
+// redactedParts are the parts of the names of the parameters never logged, e.g. "password" or "secretkey"
+var redactedParts = []string{"password", "secret"}
+
+// isSecret tells whether the parameter or header holds a secret
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range redactedKeys {
+		if key == k {
+			return true
+		}
+	}
+	for _, part := range redactedParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValues copies the parameters, their secrets being replaced by Redacted
+//
+// The secrets are the same as in the logs: the apikey, the signature, the
+// userdata, the passwords, etc.
+func RedactValues(params url.Values) url.Values {
+	values := make(url.Values, len(params))
+	for k, vv := range params {
+		if isSecret(k) {
+			vv = []string{Redacted}
+		}
+		values[k] = vv
+	}
+	return values
+}
+
+// redact copies the url.Values and http.Header of the fields, without their secrets
+func redact(fields []Field) []Field {
+	redacted := make([]Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = f
+		switch v := f.Value.(type) {
+		case url.Values:
+			redacted[i].Value = RedactValues(v)
+		case http.Header:
+			header := make(http.Header, len(v))
+			for k, vv := range v {
+				if isSecret(k) {
+					vv = []string{Redacted}
+				}
+				header[k] = vv
+			}
+			redacted[i].Value = header
+		}
+	}
+	return redacted
+}
+
+// formatValue formats the value of a field for the StdLogger
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case url.Values:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			for _, vv := range v[k] {
+				parts = append(parts, k+"="+vv)
+			}
+		}
+		return fmt.Sprintf("%q", strings.Join(parts, "&"))
+	case http.Header:
+		return formatValue(url.Values(v))
+	case string:
+		if strings.ContainsAny(v, " \"=") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case error:
+		return fmt.Sprintf("%q", v.Error())
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// log sends the entry, without the secrets, to the Logger of the client
+func (exo *Client) log(level Level, msg string, fields ...Field) {
+	if exo.Logger == nil {
+		return
+	}
+	exo.Logger.Log(level, msg, redact(fields)...)
+}
+
+// errorFields describes the error for the logs
+func errorFields(err error) []Field {
+	if err == nil {
+		return nil
+	}
+
+	fields := []Field{{"error", err}}
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		fields = append(fields, Field{"errorcode", int(e.ErrorCode)}, Field{"cserrorcode", int(e.CsErrorCode)})
+	}
+	return fields
+}
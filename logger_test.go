@@ -0,0 +1,170 @@
+package egoscale
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	entries []entry
+}
+
+func (l *recordingLogger) Log(level Level, msg string, fields ...Field) {
+	e := entry{level: level, msg: msg, fields: make(map[string]interface{})}
+	for _, f := range fields {
+		e.fields[f.Key] = f.Value
+	}
+	l.entries = append(l.entries, e)
+}
+
+func (l *recordingLogger) find(msg string) *entry {
+	for i := range l.entries {
+		if l.entries[i].msg == msg {
+			return &l.entries[i]
+		}
+	}
+	return nil
+}
+
+func TestLoggerRequest(t *testing.T) {
+	ts := newServer(response{200, `
+{"deployvirtualmachineresponse": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 1,
+	"jobresult": {"virtualmachine": {"id": "123"}}
+}}`})
+	defer ts.Close()
+
+	logger := &recordingLogger{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = ConstantRetryStrategy(0)
+	cs.Logger = logger
+
+	_, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+		UserData:          "c2VjcmV0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := logger.find("request")
+	if request == nil {
+		t.Fatalf("a request entry was expected, got %#v", logger.entries)
+	}
+	params := request.fields["params"].(url.Values)
+	if params.Get("apikey") != Redacted || params.Get("userdata") != Redacted {
+		t.Errorf("the secrets should have been redacted, got %v", params)
+	}
+	if request.fields["command"] != "deployVirtualMachine" || request.fields["status"] != 200 {
+		t.Errorf("bad request entry, got %#v", request.fields)
+	}
+
+	async := logger.find("asyncRequest")
+	if async == nil {
+		t.Fatalf("an asyncRequest entry was expected, got %#v", logger.entries)
+	}
+	if async.fields["jobid"] != "1" || async.fields["polls"] != 2 || async.fields["jobstatus"] != Success {
+		t.Errorf("bad asyncRequest entry, got %#v", async.fields)
+	}
+}
+
+func TestLoggerErrorCode(t *testing.T) {
+	ts := newServer(response{431, `
+{"listzonesresponse": {
+	"errorcode": 431,
+	"cserrorcode": 4350,
+	"errortext": "bad"
+}}`})
+	defer ts.Close()
+
+	logger := &recordingLogger{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Logger = logger
+
+	if _, err := cs.Request(&ListZones{}); err == nil {
+		t.Fatal("an error was expected")
+	}
+
+	request := logger.find("request")
+	if request == nil || request.fields["errorcode"] != 431 || request.fields["status"] != 431 {
+		t.Errorf("the error code was expected, got %#v", request)
+	}
+}
+
+func TestLoggerDNS(t *testing.T) {
+	ts := newServer(response{200, `{"domain": {"id": 1, "name": "example.com"}}`})
+	defer ts.Close()
+
+	logger := &recordingLogger{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Logger = logger
+
+	if _, err := cs.GetDomain("example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	dns := logger.find("dnsRequest")
+	if dns == nil {
+		t.Fatalf("a dnsRequest entry was expected, got %#v", logger.entries)
+	}
+	if h := dns.fields["header"].(http.Header); h.Get("X-DNS-TOKEN") != Redacted {
+		t.Errorf("the token should have been redacted, got %v", h)
+	}
+	if dns.fields["command"] != "GET /v1/domains/example.com" {
+		t.Errorf("bad command, got %v", dns.fields["command"])
+	}
+}
+
+func TestLoggerSkip(t *testing.T) {
+	logger := &recordingLogger{}
+	params := url.Values{}
+	profile := struct {
+		Name     string `json:"name"`
+		IgnoreMe string
+	}{Name: "test"}
+
+	if err := prepareValues("", &params, profile, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logger.entries) != 1 || logger.entries[0].level != LevelWarn || logger.entries[0].fields["field"] != "IgnoreMe" {
+		t.Errorf("the skipped field should have been logged, got %#v", logger.entries)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &StdLogger{Level: LevelInfo, Logger: log.New(&buf, "", 0)}
+
+	logger.Log(LevelDebug, "hidden")
+	logger.Log(LevelWarn, "field skipped", Field{"type", "Foo"}, Field{"params", url.Values{"name": {"a b"}}})
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("the debug entries should be filtered out, got %q", out)
+	}
+	if out != "[WARN] field skipped type=Foo params=\"name=a b\"\n" {
+		t.Errorf("bad output, got %q", out)
+	}
+}
@@ -0,0 +1,16 @@
+package egoscale
+
+import (
+	"time"
+)
+
+// Metrics receives the measurements of the CloudStack API calls, labelled by command
+//
+// The DNS API calls aren't measured. See the metrics package for a
+// Prometheus exporter.
+type Metrics interface {
+	// ObserveRequest is called once per request, after the retries, with how long it took and its error
+	ObserveRequest(command string, duration time.Duration, err error)
+	// ObserveJob is called when an async job is done waiting, with how long it took, the number of polls and its error
+	ObserveJob(command string, wait time.Duration, polls int, err error)
+}
@@ -0,0 +1,15 @@
+/*
+
+Package metrics exports the measurements of the egoscale clients in the Prometheus text format.
+
+	registry := metrics.NewRegistry()
+
+	cs := egoscale.NewClient(endpoint, apiKey, apiSecret)
+	cs.Metrics = registry
+
+	http.Handle("/metrics", registry)
+
+A Registry is self-contained, many of them may be used side by side, e.g. one per test.
+
+*/
+package metrics
@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exoscale/egoscale"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request duration histogram
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// DefaultWaitBuckets are the upper bounds, in seconds, of the job wait histogram
+var DefaultWaitBuckets = []float64{1, 2, 5, 10, 30, 60, 120, 300, 600}
+
+// DefaultPollBuckets are the upper bounds of the job polls histogram
+var DefaultPollBuckets = []float64{1, 2, 3, 5, 10, 20, 50}
+
+// Registry collects the metrics of the clients and exports them in the Prometheus text format
+//
+//	registry := metrics.NewRegistry()
+//	cs.Metrics = registry
+//	http.Handle("/metrics", registry)
+//
+// The exported metrics are:
+//
+//	egoscale_requests_total{command}
+//	egoscale_request_errors_total{command,code}
+//	egoscale_request_duration_seconds{command}
+//	egoscale_jobs_total{command}
+//	egoscale_job_errors_total{command,code}
+//	egoscale_job_wait_seconds{command}
+//	egoscale_job_polls{command}
+//
+// The code is the CloudStack ErrorCode, or "other" for any other error.
+type Registry struct {
+	mu              sync.Mutex
+	requests        *counter
+	requestErrors   *counter
+	requestDuration *histogram
+	jobs            *counter
+	jobErrors       *counter
+	jobWait         *histogram
+	jobPolls        *histogram
+}
+
+// NewRegistry creates an empty registry using the default buckets
+func NewRegistry() *Registry {
+	return &Registry{
+		requests:        newCounter("egoscale_requests_total", "Number of CloudStack requests."),
+		requestErrors:   newCounter("egoscale_request_errors_total", "Number of failed CloudStack requests, by error code."),
+		requestDuration: newHistogram("egoscale_request_duration_seconds", "Duration of the CloudStack requests, retries included.", DefaultDurationBuckets),
+		jobs:            newCounter("egoscale_jobs_total", "Number of async jobs waited upon."),
+		jobErrors:       newCounter("egoscale_job_errors_total", "Number of failed async jobs, by error code."),
+		jobWait:         newHistogram("egoscale_job_wait_seconds", "Time spent waiting for the async jobs.", DefaultWaitBuckets),
+		jobPolls:        newHistogram("egoscale_job_polls", "Number of polls of the async jobs.", DefaultPollBuckets),
+	}
+}
+
+// ObserveRequest records a request
+func (r *Registry) ObserveRequest(command string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests.inc(labels{"command", command})
+	r.requestDuration.observe(duration.Seconds(), labels{"command", command})
+	// a dry run didn't fail, it wasn't sent
+	if err != nil && !errors.Is(err, egoscale.ErrDryRun) {
+		r.requestErrors.inc(labels{"command", command, "code", errorCode(err)})
+	}
+}
+
+// ObserveJob records an async job
+func (r *Registry) ObserveJob(command string, wait time.Duration, polls int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs.inc(labels{"command", command})
+	r.jobWait.observe(wait.Seconds(), labels{"command", command})
+	r.jobPolls.observe(float64(polls), labels{"command", command})
+	if err != nil {
+		r.jobErrors.inc(labels{"command", command, "code", errorCode(err)})
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+//
+// The metrics are rendered first, so a slow writer doesn't hold back the
+// observations.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	buf := new(bytes.Buffer)
+	r.render(buf)
+	return buf.WriteTo(w)
+}
+
+// render writes a snapshot of the metrics into the buffer
+func (r *Registry) render(buf *bytes.Buffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests.write(buf)
+	r.requestErrors.write(buf)
+	r.requestDuration.write(buf)
+	r.jobs.write(buf)
+	r.jobErrors.write(buf)
+	r.jobWait.write(buf)
+	r.jobPolls.write(buf)
+}
+
+// ServeHTTP exports the metrics, to be scraped by Prometheus
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// errorCode returns the label of the error
+func errorCode(err error) string {
+	var e *egoscale.ErrorResponse
+	if errors.As(err, &e) {
+		return strconv.Itoa(int(e.ErrorCode))
+	}
+	return "other"
+}
+
+// labels represents the label names and values, in pairs
+type labels []string
+
+// String formats the labels the Prometheus way, e.g. {command="listZones"}
+func (l labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(l)/2)
+	for i := 0; i+1 < len(l); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", l[i], escape(l[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// with returns a copy of the labels with the extra pair
+func (l labels) with(name, value string) labels {
+	return append(append(labels{}, l...), name, value)
+}
+
+// labelEscaper escapes the label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes the label value
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// counter represents a counter with labels
+type counter struct {
+	name   string
+	help   string
+	values map[string]float64
+}
+
+func newCounter(name, help string) *counter {
+	return &counter{name: name, help: help, values: make(map[string]float64)}
+}
+
+// inc increments the counter having those labels
+func (c *counter) inc(l labels) {
+	c.values[l.String()]++
+}
+
+// write writes the counter in the text format
+func (c *counter) write(w *bytes.Buffer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+// histogram represents a histogram with labels
+type histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	series  map[string]*series
+}
+
+// series represents the observations of a histogram having the same labels
+type series struct {
+	labels labels
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	return &histogram{name: name, help: help, buckets: b, series: make(map[string]*series)}
+}
+
+// observe records the value in the series having those labels
+func (h *histogram) observe(v float64, l labels) {
+	key := l.String()
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{labels: l, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// write writes the histogram in the text format
+func (h *histogram) write(w *bytes.Buffer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, s.labels.with("le", formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, s.labels.with("le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+// formatFloat formats the value the Prometheus way
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of the map, sorted
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
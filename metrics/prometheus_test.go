@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exoscale/egoscale"
+	"github.com/exoscale/egoscale/egoscaletest"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveRequest("listZones", 70*time.Millisecond, nil)
+	r.ObserveRequest("listZones", 3*time.Second, &egoscale.ErrorResponse{ErrorCode: egoscale.ParamError})
+	r.ObserveRequest(`a"b`, time.Millisecond, errors.New("boom"))
+	r.ObserveJob("deployVirtualMachine", 12*time.Second, 4, nil)
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE egoscale_requests_total counter\n",
+		`egoscale_requests_total{command="listZones"} 2` + "\n",
+		`egoscale_request_errors_total{command="listZones",code="431"} 1` + "\n",
+		`egoscale_request_errors_total{command="a\"b",code="other"} 1` + "\n",
+		"# TYPE egoscale_request_duration_seconds histogram\n",
+		`egoscale_request_duration_seconds_bucket{command="listZones",le="0.05"} 0` + "\n",
+		`egoscale_request_duration_seconds_bucket{command="listZones",le="0.1"} 1` + "\n",
+		`egoscale_request_duration_seconds_bucket{command="listZones",le="5"} 2` + "\n",
+		`egoscale_request_duration_seconds_bucket{command="listZones",le="+Inf"} 2` + "\n",
+		`egoscale_request_duration_seconds_sum{command="listZones"} 3.07` + "\n",
+		`egoscale_request_duration_seconds_count{command="listZones"} 2` + "\n",
+		`egoscale_jobs_total{command="deployVirtualMachine"} 1` + "\n",
+		`egoscale_job_wait_seconds_bucket{command="deployVirtualMachine",le="10"} 0` + "\n",
+		`egoscale_job_wait_seconds_bucket{command="deployVirtualMachine",le="30"} 1` + "\n",
+		`egoscale_job_polls_bucket{command="deployVirtualMachine",le="5"} 1` + "\n",
+		`egoscale_job_polls_sum{command="deployVirtualMachine"} 4` + "\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q was expected in\n%s", line, out)
+		}
+	}
+}
+
+func TestRegistryDryRun(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveRequest("destroyVirtualMachine", time.Millisecond, egoscale.ErrDryRun)
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "egoscale_request_errors_total{") {
+		t.Errorf("a dry run shouldn't be counted as an error, got\n%s", buf.String())
+	}
+}
+
+func TestRegistryClient(t *testing.T) {
+	s := egoscaletest.NewServer("KEY", "SECRET")
+	defer s.Close()
+	s.PendingPolls = 1
+
+	r := NewRegistry()
+	cs := s.NewClient()
+	cs.Metrics = r
+
+	if _, err := cs.Request(&egoscale.AssociateIPAddress{ZoneID: egoscaletest.DefaultZoneID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Request(&egoscale.DisassociateIPAddress{ID: "unknown"}); err == nil {
+		t.Fatal("an error was expected")
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	expected := []string{
+		`egoscale_requests_total{command="associateIpAddress"} 1`,
+		`egoscale_requests_total{command="queryAsyncJobResult"} 2`,
+		`egoscale_request_errors_total{command="disassociateIpAddress",code="431"} 1`,
+		`egoscale_jobs_total{command="associateIpAddress"} 1`,
+		`egoscale_job_polls_sum{command="associateIpAddress"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("%q was expected in\n%s", line, out)
+		}
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("bad content type, got %q", ct)
+	}
+}
+
+// blockingWriter waits for its release before each write
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestRegistrySlowWriter(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveRequest("listZones", time.Millisecond, nil)
+
+	w := blockingWriter{release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := r.WriteTo(w); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	observed := make(chan struct{})
+	go func() {
+		r.ObserveRequest("listZones", time.Millisecond, nil)
+		close(observed)
+	}()
+
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Error("a slow writer shouldn't block the observations")
+	}
+
+	close(w.release)
+	<-done
+}
@@ -5,7 +5,7 @@ type NetworkOffering struct {
 	ID                       string            `json:"id"`
 	Availability             string            `json:"availability,omitempty"`
 	ConserveMode             bool              `json:"conservemode,omitempty"`
-	Created                  string            `json:"created"`
+	Created                  *Timestamp        `json:"created,omitempty"`
 	Details                  map[string]string `json:"details,omitempty"`
 	DisplayText              string            `json:"displaytext,omitempty"`
 	EgressDefaultPolicy      bool              `json:"egressdefaultpolicy,omitempty"`
@@ -102,21 +102,21 @@ type CreateNetwork struct {
 	ZoneID            string `json:"zoneid"`
 	Account           string `json:"account,omitempty"`
 	ACLID             string `json:"aclid,omitempty"`
-	ACLType           string `json:"acltype,omitempty"`        // Account or Domain
-	DisplayNetwork    *bool  `json:"displaynetwork,omitempty"` // root only
+	ACLType           string `json:"acltype,omitempty" validate:"oneof=Account Domain"` // Account or Domain
+	DisplayNetwork    *bool  `json:"displaynetwork,omitempty"`                          // root only
 	DomainID          string `json:"domainid,omitempty"`
-	EndIP             net.IP `json:"endip,omitempty"`
-	EndIpv6           net.IP `json:"endipv6,omitempty"`
-	Gateway           net.IP `json:"gateway,omitempty"`
-	IP6Cidr           string `json:"ip6cidr,omitempty"`
-	IP6Gateway        net.IP `json:"ip6gateway,omitempty"`
+	EndIP             net.IP `json:"endip,omitempty" validate:"ipv4"`
+	EndIpv6           net.IP `json:"endipv6,omitempty" validate:"ipv6"`
+	Gateway           net.IP `json:"gateway,omitempty" validate:"ipv4"`
+	IP6Cidr           string `json:"ip6cidr,omitempty" validate:"cidr"`
+	IP6Gateway        net.IP `json:"ip6gateway,omitempty" validate:"ipv6"`
 	IsolatedPVlan     string `json:"isolatedpvlan,omitempty"`
-	Netmask           net.IP `json:"netmask,omitempty"`
+	Netmask           net.IP `json:"netmask,omitempty" validate:"ipv4"`
 	NetworkDomain     string `json:"networkdomain,omitempty"`
 	PhysicalNetworkID string `json:"physicalnetworkid,omitempty"`
 	ProjectID         string `json:"projectid,omitempty"`
-	StartIP           net.IP `json:"startip,omitempty"`
-	StartIpv6         net.IP `json:"startipv6,omitempty"`
+	StartIP           net.IP `json:"startip,omitempty" validate:"ipv4"`
+	StartIpv6         net.IP `json:"startipv6,omitempty" validate:"ipv6"`
 	SubdomainAccess   string `json:"subdomainaccess,omitempty"`
 	Vlan              string `json:"vlan,omitempty"`
 	VpcID             string `json:"vpcid,omitempty"`
@@ -38,8 +38,13 @@ func (ls *ListNics) SetPageSize(pageSize int) {
 	ls.PageSize = pageSize
 }
 
-func (*ListNics) each(resp interface{}, callback IterateItemFunc) {
-	nics := resp.(*ListNicsResponse)
+func (ls *ListNics) each(resp interface{}, callback IterateItemFunc) {
+	nics, ok := resp.(*ListNicsResponse)
+	if !ok {
+		callback(nil, &UnexpectedResponseError{Command: ls.APIName(), Response: resp})
+		return
+	}
+
 	for _, nic := range nics.Nic {
 		if !callback(nic, nil) {
 			break
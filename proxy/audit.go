@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// Entry represents a mutating call in the audit log
+//
+// The secrets of the parameters, e.g. the userdata or the passwords, are redacted.
+type Entry struct {
+	Time    time.Time  `json:"time"`
+	APIKey  string     `json:"apikey,omitempty"`
+	Remote  string     `json:"remote"`
+	Command string     `json:"command"`
+	Params  url.Values `json:"params,omitempty"`
+	Status  int        `json:"status"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// record appends the entry to the audit log
+func (p *Proxy) record(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	p.auditMu.Lock()
+	defer p.auditMu.Unlock()
+
+	_, err = p.Audit.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,29 @@
+/*
+
+Package proxy provides a CloudStack proxy holding the real credentials, so that the users don't have to.
+
+The requests sent to the proxy are either unsigned or signed using local keys. They are checked against a policy, signed again using the central credentials and forwarded to CloudStack.
+
+	p := proxy.New("https://api.exoscale.ch/compute", egoscale.NewStaticCredentials(apiKey, apiSecret))
+	p.Keys = map[string]string{
+		"EXOdev": "local secret",
+	}
+	p.Policy = &proxy.Policy{
+		Allow: []string{"list*", "queryAsyncJobResult", "deployVirtualMachine", "stopVirtualMachine"},
+		Deny:  []string{"expungeVirtualMachine"},
+		Params: map[string][]string{
+			"zoneid": {"1128bd56-b4d9-4ac6-a7b9-c715b187ce11"},
+		},
+	}
+	p.Audit = auditFile
+
+	log.Fatal(http.ListenAndServe(":8080", p))
+
+The clients simply use the proxy as their endpoint.
+
+	cs := egoscale.NewClient("http://localhost:8080", "EXOdev", "local secret")
+
+Every mutating call, i.e. not a list*, get* or query* command, is written to the audit log whether it was allowed or not.
+
+*/
+package proxy
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Policy represents what the requests going through the proxy may do
+//
+// The commands are matched case insensitively, using the path.Match
+// patterns, e.g. "list*". A denied command is always refused. When Allow
+// is set, only the allowed commands go through.
+//
+//	policy := &proxy.Policy{
+//		Allow: []string{"list*", "queryAsyncJobResult", "deployVirtualMachine"},
+//		Deny:  []string{"expungeVirtualMachine"},
+//		Params: map[string][]string{
+//			"zoneid": {"1128bd56-b4d9-4ac6-a7b9-c715b187ce11"},
+//		},
+//	}
+type Policy struct {
+	// Allow lists the allowed commands, all of them when empty
+	Allow []string
+	// Deny lists the refused commands
+	Deny []string
+	// Params restricts the values of the given parameters, when they are present
+	Params map[string][]string
+}
+
+// PolicyError represents a request refused by the policy
+type PolicyError struct {
+	Command string
+	Reason  string
+}
+
+// Error formats the error
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("The command %s is not allowed: %s", e.Command, e.Reason)
+}
+
+// Check tells whether the command with those parameters is allowed, a PolicyError is returned otherwise
+func (p *Policy) Check(command string, params url.Values) error {
+	if p == nil {
+		return nil
+	}
+
+	if match(p.Deny, command) {
+		return &PolicyError{Command: command, Reason: "denied"}
+	}
+
+	if len(p.Allow) > 0 && !match(p.Allow, command) {
+		return &PolicyError{Command: command, Reason: "not in the allowed commands"}
+	}
+
+	for name, allowed := range p.Params {
+		for key, values := range params {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			for _, value := range values {
+				for _, v := range strings.Split(value, ",") {
+					if !contains(allowed, v) {
+						return &PolicyError{Command: command, Reason: fmt.Sprintf("%s=%s is restricted", name, v)}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// match tells whether the command matches any of the patterns
+func match(patterns []string, command string) bool {
+	command = strings.ToLower(command)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), command); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// contains tells whether the value is in the list
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
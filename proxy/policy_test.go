@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		Allow: []string{"list*", "deployVirtualMachine", "expungeVirtualMachine"},
+		Deny:  []string{"expungeVirtualMachine"},
+		Params: map[string][]string{
+			"zoneid":           {"1"},
+			"securitygroupids": {"a", "b"},
+		},
+	}
+
+	tests := []struct {
+		command string
+		params  url.Values
+		allowed bool
+	}{
+		{"listZones", url.Values{}, true},
+		{"listzones", url.Values{}, true},
+		{"listVirtualMachines", url.Values{"zoneid": {"1"}}, true},
+		{"listVirtualMachines", url.Values{"zoneid": {"2"}}, false},
+		{"deployVirtualMachine", url.Values{"zoneid": {"1"}, "securitygroupids": {"a,b"}}, true},
+		{"deployVirtualMachine", url.Values{"zoneid": {"1"}, "securitygroupids": {"a,c"}}, false},
+		{"deployVirtualMachine", url.Values{"ZoneId": {"2"}}, false},
+		{"expungeVirtualMachine", url.Values{}, false},
+		{"destroyVirtualMachine", url.Values{}, false},
+	}
+
+	for _, test := range tests {
+		err := policy.Check(test.command, test.params)
+		if test.allowed && err != nil {
+			t.Errorf("%s %v should be allowed, got %v", test.command, test.params, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("%s %v should be refused", test.command, test.params)
+		}
+	}
+
+	var none *Policy
+	if err := none.Check("expungeVirtualMachine", url.Values{}); err != nil {
+		t.Errorf("no policy should allow everything, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exoscale/egoscale"
+)
+
+// authParams are the parameters carrying the credentials of the client, they never reach CloudStack
+var authParams = []string{"apikey", "signature", "signatureversion", "expires"}
+
+// Proxy is an http.Handler forwarding the CloudStack requests, signed with central credentials
+//
+// The incoming requests are checked against the Policy, then signed again
+// using the Credentials and sent to the Endpoint. The mutating calls,
+// allowed or not, are written to the Audit log.
+type Proxy struct {
+	// Endpoint is the CloudStack endpoint the requests are forwarded to
+	Endpoint string
+	// Credentials holds the central credentials used to sign the requests
+	Credentials egoscale.CredentialsProvider
+	// Keys holds the local API keys and their secrets, when empty the requests don't have to be signed
+	Keys map[string]string
+	// Policy restricts the commands going through, if set
+	Policy *Policy
+	// HTTPClient sends the requests to CloudStack
+	HTTPClient *http.Client
+	// Audit receives the audit log of the mutating calls, as JSON lines, if set
+	Audit io.Writer
+	// Logger reports the failures of the proxy itself
+	Logger egoscale.Logger
+
+	auditMu sync.Mutex
+}
+
+// New creates a proxy to the endpoint using the given credentials
+func New(endpoint string, credentials egoscale.CredentialsProvider) *Proxy {
+	return &Proxy{
+		Endpoint:    endpoint,
+		Credentials: credentials,
+		HTTPClient:  &http.Client{Timeout: egoscale.DefaultTimeout},
+		Logger:      egoscale.DefaultLogger,
+	}
+}
+
+// ServeHTTP checks, signs and forwards the CloudStack request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := r.Form
+	if len(params["command"]) > 1 {
+		// the policy would only check the first one while all of them are forwarded
+		http.Error(w, "command is given more than once", http.StatusBadRequest)
+		return
+	}
+
+	command := params.Get("command")
+	if command == "" {
+		http.Error(w, "command is missing", http.StatusBadRequest)
+		return
+	}
+
+	entry := &Entry{
+		Time:    time.Now().UTC(),
+		APIKey:  params.Get("apikey"),
+		Remote:  r.RemoteAddr,
+		Command: command,
+		Params:  egoscale.RedactValues(scrub(params)),
+	}
+
+	status, err := p.serve(w, r, command, params)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.Status = status
+
+	if p.Audit != nil && !egoscale.IsReadOnly(command) {
+		if err := p.record(entry); err != nil && p.Logger != nil {
+			p.Logger.Log(egoscale.LevelError, "the audit log cannot be written", egoscale.Field{Key: "error", Value: err})
+		}
+	}
+}
+
+// serve handles the request, it returns the HTTP status sent back and why it failed
+func (p *Proxy) serve(w http.ResponseWriter, r *http.Request, command string, params url.Values) (int, error) {
+	if err := p.authenticate(params); err != nil {
+		return writeError(w, command, egoscale.Unauthorized, egoscale.CloudAuthenticationException, err)
+	}
+
+	if err := p.Policy.Check(command, params); err != nil {
+		return writeError(w, command, egoscale.Unauthorized, egoscale.PermissionDeniedException, err)
+	}
+
+	resp, err := p.forward(r, params)
+	if err != nil {
+		return writeError(w, command, egoscale.InternalError, egoscale.ServerAPIException, err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
+
+// authenticate checks the local signature of the request, if any key is known
+func (p *Proxy) authenticate(params url.Values) error {
+	if len(p.Keys) == 0 {
+		return nil
+	}
+
+	secret, ok := p.Keys[params.Get("apikey")]
+	if !ok {
+		return fmt.Errorf("Unable to verify user credentials and/or request signature")
+	}
+
+	if err := egoscale.VerifySignature(params, secret); err != nil {
+		return fmt.Errorf("Unable to verify user credentials and/or request signature: %s", err)
+	}
+	return nil
+}
+
+// forward signs the parameters with the central credentials and sends them to CloudStack
+func (p *Proxy) forward(r *http.Request, params url.Values) (*http.Response, error) {
+	creds, err := p.Credentials.Credentials(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	values := scrub(params)
+	values.Set("apikey", creds.APIKey)
+	values.Set("signature", egoscale.Sign(values, creds.APISecret))
+
+	payload := values.Encode()
+	req, err := http.NewRequest("POST", p.Endpoint, strings.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(r.Context())
+
+	return p.HTTPClient.Do(req)
+}
+
+// scrub copies the parameters without the credentials
+func scrub(params url.Values) url.Values {
+	values := url.Values{}
+	for k, v := range params {
+		values[k] = v
+	}
+	for _, k := range authParams {
+		values.Del(k)
+	}
+	return values
+}
+
+// writeError sends the error the way CloudStack does
+func writeError(w http.ResponseWriter, command string, code egoscale.ErrorCode, csCode egoscale.CSErrorCode, err error) (int, error) {
+	b, e := json.Marshal(map[string]interface{}{
+		strings.ToLower(command) + "response": &egoscale.ErrorResponse{
+			ErrorCode:   code,
+			CsErrorCode: csCode,
+			ErrorText:   err.Error(),
+		},
+	})
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return http.StatusInternalServerError, e
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(int(code))
+	w.Write(b)
+	return int(code), err
+}
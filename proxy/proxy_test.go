@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/exoscale/egoscale"
+	"github.com/exoscale/egoscale/egoscaletest"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := egoscaletest.NewServer("CENTRAL", "CENTRALSECRET")
+	defer upstream.Close()
+
+	var audit bytes.Buffer
+	p := New(upstream.URL, egoscale.NewStaticCredentials("CENTRAL", "CENTRALSECRET"))
+	p.Keys = map[string]string{"DEV": "DEVSECRET"}
+	p.Policy = &Policy{
+		Allow: []string{"list*", "createSecurityGroup"},
+		Params: map[string][]string{
+			"zoneid": {egoscaletest.DefaultZoneID},
+		},
+	}
+	p.Audit = &audit
+
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	cs := egoscale.NewClient(ts.URL, "DEV", "DEVSECRET")
+
+	if _, err := cs.Request(&egoscale.ListVirtualMachines{ZoneID: egoscaletest.DefaultZoneID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cs.Request(&egoscale.ListVirtualMachines{ZoneID: "other"}); !errors.Is(err, egoscale.ErrUnauthorized) {
+		t.Errorf("the zone is restricted, ErrUnauthorized was expected, got %v", err)
+	}
+
+	resp, err := cs.Request(&egoscale.CreateSecurityGroup{Name: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg := resp.(*egoscale.CreateSecurityGroupResponse).SecurityGroup; sg.Name != "web" {
+		t.Errorf("bad security group, got %#v", sg)
+	}
+
+	if err := cs.BooleanRequest(&egoscale.DeleteSecurityGroup{Name: "web"}); !errors.Is(err, egoscale.ErrUnauthorized) {
+		t.Errorf("ErrUnauthorized was expected, got %v", err)
+	}
+
+	other := egoscale.NewClient(ts.URL, "DEV", "WRONG")
+	if _, err := other.Request(&egoscale.ListZones{}); !errors.Is(err, egoscale.ErrUnauthorized) {
+		t.Errorf("a bad signature should be refused, got %v", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(&audit)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("only the mutating calls should be audited, got %#v", entries)
+	}
+	if entries[0].Command != "createSecurityGroup" || entries[0].Status != 200 || entries[0].APIKey != "DEV" {
+		t.Errorf("bad entry, got %#v", entries[0])
+	}
+	if entries[1].Command != "deleteSecurityGroup" || entries[1].Status != 401 || entries[1].Error == "" {
+		t.Errorf("bad entry, got %#v", entries[1])
+	}
+	if entries[0].Params.Get("signature") != "" {
+		t.Errorf("the signature should have been scrubbed, got %v", entries[0].Params)
+	}
+}
+
+func TestProxyAuditRedacted(t *testing.T) {
+	upstream := egoscaletest.NewServer("CENTRAL", "CENTRALSECRET")
+	defer upstream.Close()
+
+	var audit bytes.Buffer
+	p := New(upstream.URL, egoscale.NewStaticCredentials("CENTRAL", "CENTRALSECRET"))
+	p.Audit = &audit
+
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	cs := egoscale.NewClient(ts.URL, "DEV", "DEVSECRET")
+	_, err := cs.Request(&egoscale.DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            egoscaletest.DefaultZoneID,
+		UserData:          "I2Nsb3VkLWNvbmZpZwpwYXNzd29yZDogaHVudGVyMgo=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(audit.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if v := entry.Params.Get("userdata"); v != egoscale.Redacted {
+		t.Errorf("the userdata should have been redacted, got %q", v)
+	}
+	if v := entry.Params.Get("zoneid"); v != egoscaletest.DefaultZoneID {
+		t.Errorf("the zoneid should have been kept, got %q", v)
+	}
+}
+
+func TestProxyDuplicateCommand(t *testing.T) {
+	upstream := egoscaletest.NewServer("CENTRAL", "CENTRALSECRET")
+	defer upstream.Close()
+
+	p := New(upstream.URL, egoscale.NewStaticCredentials("CENTRAL", "CENTRALSECRET"))
+	p.Policy = &Policy{Allow: []string{"list*"}}
+
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	resp, err := http.PostForm(ts.URL, url.Values{"command": {"listZones", "destroyVirtualMachine"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("a request with many commands should be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyUnsigned(t *testing.T) {
+	upstream := egoscaletest.NewServer("CENTRAL", "CENTRALSECRET")
+	defer upstream.Close()
+
+	ts := httptest.NewServer(New(upstream.URL, egoscale.NewStaticCredentials("CENTRAL", "CENTRALSECRET")))
+	defer ts.Close()
+
+	// without any local keys, whatever the client uses is replaced
+	cs := egoscale.NewClient(ts.URL, "ANYTHING", "ANYTHING")
+	if _, err := cs.Request(&egoscale.ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,167 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the rate of the requests sent to the API
+//
+// The limiter is adaptive: every time the API answers with APILimitExceeded,
+// the rate is halved (down to a tenth of its initial value). It then recovers
+// slowly, with every successful request, back to the initial rate.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // current number of tokens per second
+	maxRate float64
+	minRate float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rate requests per second with bursts of burst requests
+//
+// The rate must be positive, nothing could ever be sent otherwise.
+func NewRateLimiter(rate float64, burst int) (*RateLimiter, error) {
+	if !(rate > 0) {
+		return nil, fmt.Errorf("%w: the rate of a RateLimiter must be positive, got %v", ErrInvalidParameter, rate)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		maxRate: rate,
+		minRate: rate / 10,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}, nil
+}
+
+// Rate returns the current rate, in requests per second
+func (rl *RateLimiter) Rate() float64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	return rl.rate
+}
+
+// Wait blocks until a request may be sent or the context is done
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	rl.mu.Lock()
+	rl.refill()
+	rl.tokens--
+	var delay time.Duration
+	if rl.tokens < 0 {
+		delay = time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+	}
+	rl.mu.Unlock()
+
+	if err := sleep(ctx, delay); err != nil {
+		// give the reserved token back
+		rl.mu.Lock()
+		rl.tokens++
+		rl.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// slowDown slows down the rate after an APILimitExceeded
+func (rl *RateLimiter) slowDown() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill()
+	rl.rate /= 2
+	if rl.rate < rl.minRate {
+		rl.rate = rl.minRate
+	}
+}
+
+// speedUp speeds up the rate after a successful request
+func (rl *RateLimiter) speedUp() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.rate < rl.maxRate {
+		rl.refill()
+		rl.rate += rl.minRate / 10
+		if rl.rate > rl.maxRate {
+			rl.rate = rl.maxRate
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last call, the lock must be held
+func (rl *RateLimiter) refill() {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+}
+
+// ThrottleFunc represents the callback called when a request is throttled by the API
+//
+// The attempt starts at one and delay is the time waited before retrying.
+type ThrottleFunc func(command string, attempt int, delay time.Duration)
+
+// isThrottled tells if the error is, or wraps, an APILimitExceeded one
+func isThrottled(err error) bool {
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		return e.ErrorCode == APILimitExceeded
+	}
+	return false
+}
+
+// throttleDelay computes the time to wait before retrying a throttled request
+//
+// The Retry-After header has precedence over the exponential backoff (1s, 2s, 4s, ... up to 1m)
+func throttleDelay(header http.Header, attempt int) time.Duration {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			if delay := time.Until(date); delay > 0 {
+				return delay
+			}
+			return 0
+		}
+	}
+
+	delay := time.Second << uint(attempt)
+	if delay > time.Minute || delay <= 0 {
+		delay = time.Minute
+	}
+	return delay
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
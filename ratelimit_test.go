@@ -0,0 +1,216 @@
+package egoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const throttledResponse = `
+{"listzonesresponse": {
+	"uuidList": [],
+	"errorcode": 429,
+	"cserrorcode": 4395,
+	"errortext": "Too many requests"
+}}`
+
+func newThrottlingServer(throttled int, retryAfter string) *httptest.Server {
+	i := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		i++
+		if i <= throttled {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(429)
+			w.Write([]byte(throttledResponse))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"listzonesresponse": {"count": 0, "zone": []}}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl, err := NewRateLimiter(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := rl.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("the rate limiter didn't slow down the requests, took %v", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	rl, err := NewRateLimiter(0.1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := rl.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("a deadline error was expected, got %v", err)
+	}
+}
+
+func TestRateLimiterAdaptive(t *testing.T) {
+	rl, err := NewRateLimiter(10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl.slowDown()
+	if rl.Rate() != 5 {
+		t.Errorf("the rate should have been halved, got %v", rl.Rate())
+	}
+
+	for i := 0; i < 10; i++ {
+		rl.slowDown()
+	}
+	if rl.Rate() != 1 {
+		t.Errorf("the rate should not go below a tenth of the initial rate, got %v", rl.Rate())
+	}
+
+	for i := 0; i < 1000; i++ {
+		rl.speedUp()
+	}
+	if rl.Rate() != 10 {
+		t.Errorf("the rate should have recovered, got %v", rl.Rate())
+	}
+}
+
+func TestRateLimiterInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		rl, err := NewRateLimiter(rate, 1)
+		if !errors.Is(err, ErrInvalidParameter) || rl != nil {
+			t.Errorf("%v: an invalid parameter error was expected, got %v", rate, err)
+		}
+	}
+}
+
+func TestRateLimiterAdaptiveSlowRate(t *testing.T) {
+	rl, err := NewRateLimiter(0.001, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		rl.slowDown()
+	}
+	if rl.Rate() != 0.0001 {
+		t.Errorf("the rate should not go below a tenth of the initial rate, got %v", rl.Rate())
+	}
+
+	for i := 0; i < 1000; i++ {
+		rl.speedUp()
+	}
+	if rl.Rate() != 0.001 {
+		t.Errorf("the rate should have recovered, got %v", rl.Rate())
+	}
+}
+
+func TestIsThrottled(t *testing.T) {
+	throttled := &ErrorResponse{ErrorCode: APILimitExceeded}
+	if !isThrottled(throttled) {
+		t.Errorf("%v is throttled", throttled)
+	}
+	if wrapped := fmt.Errorf("interceptor: %w", throttled); !isThrottled(wrapped) {
+		t.Errorf("%v is throttled", wrapped)
+	}
+	if isThrottled(&ErrorResponse{ErrorCode: InternalError}) {
+		t.Errorf("an internal error isn't throttled")
+	}
+}
+
+func TestRequestThrottled(t *testing.T) {
+	ts := newThrottlingServer(2, "0")
+	defer ts.Close()
+
+	attempts := make([]int, 0)
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	rl, err := NewRateLimiter(100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.RateLimiter = rl
+	cs.OnThrottle = func(command string, attempt int, delay time.Duration) {
+		if command != "listZones" {
+			t.Errorf("bad command, got %q", command)
+		}
+		if delay != 0 {
+			t.Errorf("Retry-After wasn't honoured, got %v", delay)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("two throttled attempts were expected, got %v", attempts)
+	}
+
+	if cs.RateLimiter.Rate() >= 100 {
+		t.Errorf("the rate limiter should have slowed down, got %v", cs.RateLimiter.Rate())
+	}
+}
+
+func TestRequestThrottledTooManyTimes(t *testing.T) {
+	ts := newThrottlingServer(10, "0")
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.ThrottleRetries = 1
+
+	_, err := cs.Request(&ListZones{})
+	if err == nil {
+		t.Fatal("an error was expected")
+	}
+
+	r, ok := err.(*ErrorResponse)
+	if !ok || r.ErrorCode != APILimitExceeded {
+		t.Errorf("an APILimitExceeded error was expected, got %v", err)
+	}
+}
+
+func TestThrottleDelay(t *testing.T) {
+	h := http.Header{}
+	if d := throttleDelay(h, 0); d != time.Second {
+		t.Errorf("1s was expected, got %v", d)
+	}
+	if d := throttleDelay(h, 3); d != 8*time.Second {
+		t.Errorf("8s was expected, got %v", d)
+	}
+	if d := throttleDelay(h, 30); d != time.Minute {
+		t.Errorf("1m was expected, got %v", d)
+	}
+
+	h.Set("Retry-After", "12")
+	if d := throttleDelay(h, 0); d != 12*time.Second {
+		t.Errorf("12s was expected, got %v", d)
+	}
+
+	h.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if d := throttleDelay(h, 0); d != 0 {
+		t.Errorf("0s was expected, got %v", d)
+	}
+}
@@ -1,17 +1,12 @@
 package egoscale
 
 import (
-	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -47,10 +42,14 @@ func (exo *Client) parseResponse(resp *http.Response) (json.RawMessage, error) {
 	a, err := rawValues(b)
 
 	if a == nil {
-		b, err = rawValue(b)
+		v, err := rawValue(b)
 		if err != nil {
+			if resp.StatusCode >= 400 {
+				return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: b}
+			}
 			return nil, err
 		}
+		b = v
 	}
 
 	if resp.StatusCode >= 400 {
@@ -58,66 +57,52 @@ func (exo *Client) parseResponse(resp *http.Response) (json.RawMessage, error) {
 		if json.Unmarshal(b, errorResponse) == nil {
 			return nil, errorResponse
 		}
-		return nil, fmt.Errorf("%d %s", resp.StatusCode, b)
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: b}
 	}
 
 	return b, nil
 }
 
 // asyncRequest perform an asynchronous job with a context
-func (exo *Client) asyncRequest(ctx context.Context, request asyncCommand) (interface{}, error) {
-	body, err := exo.request(ctx, request.APIName(), request)
+func (exo *Client) asyncRequest(ctx context.Context, request asyncCommand) (response interface{}, err error) {
+	var job *AsyncJob
+	start := time.Now()
+	defer func() {
+		fields := []Field{
+			{"command", request.APIName()},
+			{"duration", time.Since(start)},
+		}
+		if job != nil {
+			fields = append(fields, Field{"jobid", job.JobID}, Field{"jobstatus", job.Status()}, Field{"polls", job.polls})
+
+			span := spanFromContext(ctx)
+			span.SetAttribute("jobid", job.JobID)
+			span.SetAttribute("jobstatus", job.Status())
+		}
+		exo.log(LevelDebug, "asyncRequest", append(fields, errorFields(err)...)...)
+	}()
+
+	job, err = exo.submit(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	jobResult := new(AsyncJobResult)
-	if err := json.Unmarshal(body, jobResult); err != nil {
-		r := new(ErrorResponse)
-		if e := json.Unmarshal(body, r); e != nil {
-			return nil, r
-		}
+	if err := job.Wait(ctx); err != nil {
 		return nil, err
 	}
 
-	// Successful response
-	if jobResult.JobID == "" || jobResult.JobStatus != Pending {
-		response := request.asyncResponse()
-		if err := json.Unmarshal(*(jobResult.JobResult), response); err != nil {
-			return nil, err
-		}
-		return response, nil
+	response = request.asyncResponse()
+	if err = job.Result(response); err != nil {
+		return nil, err
 	}
 
-	for iteration := 0; ; iteration++ {
-		time.Sleep(exo.RetryStrategy(int64(iteration)))
-
-		req := &QueryAsyncJobResult{JobID: jobResult.JobID}
-		resp, err := exo.syncRequest(ctx, req)
-		if err != nil {
+	// the synthetic result of a dry run doesn't fit any response
+	if job.result.JobResult != nil && !job.dryRun {
+		if err = exo.strict(request.APIName(), *job.result.JobResult, response); err != nil {
 			return nil, err
 		}
-
-		result, ok := resp.(*QueryAsyncJobResultResponse)
-		if !ok {
-			return nil, resp.(*ErrorResponse)
-		}
-
-		if result.JobStatus == Success {
-			response := request.asyncResponse()
-			if err := json.Unmarshal(*(result.JobResult), response); err != nil {
-				return nil, err
-			}
-			return response, nil
-
-		} else if result.JobStatus == Failure {
-			r := new(ErrorResponse)
-			if e := json.Unmarshal(*result.JobResult, r); e != nil {
-				return nil, e
-			}
-			return nil, r
-		}
 	}
+	return response, nil
 }
 
 // syncRequest performs a sync request with a context
@@ -130,32 +115,27 @@ func (exo *Client) syncRequest(ctx context.Context, request syncCommand) (interf
 	response := request.response()
 	if err := json.Unmarshal(body, response); err != nil {
 		errResponse := new(ErrorResponse)
-		if json.Unmarshal(body, errResponse) == nil {
-			return errResponse, nil
+		if json.Unmarshal(body, errResponse) == nil && errResponse.ErrorCode != 0 {
+			return nil, errResponse
+		}
+		if e := exo.strict(request.APIName(), body, response); e != nil {
+			return nil, e
 		}
 		return nil, err
 	}
 
+	if err := exo.strict(request.APIName(), body, response); err != nil {
+		return nil, err
+	}
 	return response, nil
 }
 
 // BooleanRequest performs the given boolean command
 func (exo *Client) BooleanRequest(req Command) error {
-	resp, err := exo.Request(req)
-	if err != nil {
-		return err
-	}
-
-	// CloudStack returns a different type between sync and async success responses
-	if b, ok := resp.(*booleanSyncResponse); ok {
-		return b.Error()
-	}
-
-	if b, ok := resp.(*booleanAsyncResponse); ok {
-		return b.Error()
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), exo.Timeout)
+	defer cancel()
 
-	panic(fmt.Errorf("The command %s is not a proper boolean response. %#v", req.APIName(), resp))
+	return exo.BooleanRequestWithContext(ctx, req)
 }
 
 // BooleanRequestWithContext performs the given boolean command
@@ -173,7 +153,7 @@ func (exo *Client) BooleanRequestWithContext(ctx context.Context, req Command) e
 		return b.Error()
 	}
 
-	panic(fmt.Errorf("The command %s is not a proper boolean response. %#v", req.APIName(), resp))
+	return &UnexpectedResponseError{Command: req.APIName(), Response: resp}
 }
 
 // Request performs the given command
@@ -181,90 +161,192 @@ func (exo *Client) Request(request Command) (interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), exo.Timeout)
 	defer cancel()
 
-	switch request.(type) {
-	case syncCommand:
-		return exo.syncRequest(ctx, request.(syncCommand))
-	case asyncCommand:
-		return exo.asyncRequest(ctx, request.(asyncCommand))
-	default:
-		panic(fmt.Errorf("The command %s is not a proper Sync or Async command", request.APIName()))
-	}
+	return exo.RequestWithContext(ctx, request)
 }
 
 // RequestWithContext preforms a request with a context
-func (exo *Client) RequestWithContext(ctx context.Context, request Command) (interface{}, error) {
+func (exo *Client) RequestWithContext(ctx context.Context, request Command) (resp interface{}, err error) {
+	if request == nil {
+		return nil, ErrNilCommand
+	}
+
+	ctx, span := exo.startSpan(ctx, "egoscale.Request", request.APIName())
+	defer func() {
+		endSpan(span, err)
+	}()
+
 	switch request.(type) {
 	case syncCommand:
 		return exo.syncRequest(ctx, request.(syncCommand))
 	case asyncCommand:
 		return exo.asyncRequest(ctx, request.(asyncCommand))
 	default:
-		panic(fmt.Errorf("The command %s is not a proper Sync or Async command", request.APIName()))
+		return nil, &UnsupportedCommandError{Command: request.APIName()}
 	}
 }
 
 // request makes a Request while being close to the metal
-func (exo *Client) request(ctx context.Context, command string, req interface{}) (json.RawMessage, error) {
-	params := url.Values{}
-	err := prepareValues("", &params, req)
+//
+// APILimitExceeded errors are retried, up to ThrottleRetries times, after
+// waiting what the API asked for via Retry-After or using an exponential backoff.
+// Transient failures of the idempotent commands are retried following the RetryPolicy.
+// In DryRun mode, the mutating commands are logged and ErrDryRun is returned.
+func (exo *Client) request(ctx context.Context, command string, req interface{}) (body json.RawMessage, err error) {
+	var params url.Values
+	status, attempts := 0, 0
+	start := time.Now()
+	defer func() {
+		fields := []Field{
+			{"command", command},
+			{"params", params},
+			{"duration", time.Since(start)},
+			{"status", status},
+			{"attempts", attempts},
+		}
+		exo.log(LevelDebug, "request", append(fields, errorFields(err)...)...)
+		if exo.Metrics != nil {
+			exo.Metrics.ObserveRequest(command, time.Since(start), err)
+		}
+	}()
+
+	params, err = commandValues(req, exo.Logger)
 	if err != nil {
 		return nil, err
 	}
-	if hookReq, ok := req.(onBeforeHook); ok {
-		hookReq.onBeforeSend(&params)
+	if exo.DryRun && !IsReadOnly(command) {
+		return nil, exo.dryRun(command, params)
 	}
-	params.Set("apikey", exo.apiKey)
+	creds, err := exo.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Set("apikey", creds.APIKey)
 	params.Set("command", command)
 	params.Set("response", "json")
 
-	// This code is borrowed from net/url/url.go
-	// The way it's encoded by net/url doesn't match
-	// how CloudStack works.
-	var buf bytes.Buffer
-	keys := make([]string, 0, len(params))
-	for k := range params {
-		keys = append(keys, k)
+	if err := exo.beforeSign(ctx, command, params); err != nil {
+		return nil, err
 	}
 
-	sort.Strings(keys)
-	for _, k := range keys {
-		prefix := csEncode(k) + "="
-		for _, v := range params[k] {
-			if buf.Len() > 0 {
-				buf.WriteByte('&')
+	throttled, retried := 0, 0
+	for {
+		if exo.RateLimiter != nil {
+			if err := exo.RateLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		attempts++
+		var header http.Header
+		body, header, status, err = exo.send(ctx, command, params, creds.APISecret)
+		if err == nil {
+			if exo.RateLimiter != nil {
+				exo.RateLimiter.speedUp()
+			}
+			return body, nil
+		}
+
+		var delay time.Duration
+		if isThrottled(err) {
+			throttled++
+			if throttled > exo.ThrottleRetries {
+				return nil, err
+			}
+
+			if exo.RateLimiter != nil {
+				exo.RateLimiter.slowDown()
+			}
+
+			delay = throttleDelay(header, throttled-1)
+			if exo.OnThrottle != nil {
+				exo.OnThrottle(command, throttled, delay)
+			}
+		} else {
+			policy := exo.RetryPolicy
+			// the context of the caller being done, it's over whatever the error
+			if policy == nil || !isIdempotent(command) || ctx.Err() != nil || !IsRetryable(err) {
+				return nil, err
+			}
+
+			retried++
+			if retried > policy.MaxRetries {
+				return nil, err
+			}
+
+			delay = policy.delay(retried)
+			if policy.OnRetry != nil {
+				policy.OnRetry(command, retried, err, delay)
 			}
-			buf.WriteString(prefix)
-			buf.WriteString(csEncode(v))
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
 		}
 	}
+}
 
-	query := buf.String()
+// commandValues validates the command and builds its parameters, the skipped fields are reported to the logger
+func commandValues(req interface{}, logger Logger) (url.Values, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	if err := prepareValues("", &params, req, logger); err != nil {
+		return nil, err
+	}
+	if hookReq, ok := req.(onBeforeHook); ok {
+		if err := hookReq.onBeforeSend(&params); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
 
-	mac := hmac.New(sha1.New, []byte(exo.apiSecret))
-	mac.Write([]byte(strings.ToLower(query)))
-	signature := csEncode(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+// send signs the parameters using the secret and performs the HTTP request
+func (exo *Client) send(ctx context.Context, command string, params url.Values, secret string) (body json.RawMessage, header http.Header, status int, err error) {
+	ctx, span := exo.startSpan(ctx, "egoscale.http", command)
+	for _, attr := range []string{"page", "jobid"} {
+		if v := params.Get(attr); v != "" {
+			span.SetAttribute(attr, v)
+		}
+	}
+	defer func() {
+		span.SetAttribute("status", status)
+		endSpan(span, err)
+	}()
 
-	payload := fmt.Sprintf("%s&signature=%s", csQuotePlus(query), signature)
+	payload := exo.signedQuery(params, secret)
 
 	request, err := http.NewRequest("POST", exo.endpoint, strings.NewReader(payload))
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
 
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	request.Header.Add("Content-Length", strconv.Itoa(len(payload)))
 	request = request.WithContext(ctx)
 
-	resp, err := exo.client.Do(request)
+	if err := exo.afterSign(ctx, command, request); err != nil {
+		return nil, nil, 0, err
+	}
+
+	resp, err := exo.do(ctx, command, request)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	body, err := exo.parseResponse(resp)
+	body, err = exo.parseResponse(resp)
 	if err != nil {
-		return nil, err
+		if _, ok := err.(*ErrorResponse); !ok && resp.StatusCode == http.StatusTooManyRequests {
+			err = &ErrorResponse{
+				ErrorCode: APILimitExceeded,
+				ErrorText: err.Error(),
+			}
+		}
+		return nil, resp.Header, resp.StatusCode, err
 	}
 
-	return body, nil
+	return body, resp.Header, resp.StatusCode, nil
 }
@@ -93,11 +93,81 @@ const (
 // See: https://github.com/apache/cloudstack/blob/master/api/src/org/apache/cloudstack/api/ApiErrorCode.java
 type ErrorCode int
 
+const (
+	// CloudRuntimeException represents an unexpected error within CloudStack
+	CloudRuntimeException CSErrorCode = 4250
+	// ExecutionException represents a failed execution
+	ExecutionException = 4255
+	// HypervisorVersionChangedException represents a change of the hypervisor version
+	HypervisorVersionChangedException = 4260
+	// CloudException represents a generic CloudStack error
+	CloudException = 4265
+	// AccountLimitException represents an account that reached its limits
+	AccountLimitException = 4275
+	// AgentUnavailableException represents an unreachable agent
+	AgentUnavailableException = 4280
+	// CloudAuthenticationException represents a failed authentication
+	CloudAuthenticationException = 4285
+	// ConcurrentOperationException represents a conflicting operation in progress
+	ConcurrentOperationException = 4290
+	// ConflictingNetworkSettingsException represents conflicting network settings
+	ConflictingNetworkSettingsException = 4295
+	// DiscoveredWithErrorException represents a resource discovered with errors
+	DiscoveredWithErrorException = 4300
+	// HAStateException represents an invalid HA state
+	HAStateException = 4305
+	// InsufficientAddressCapacityException represents a lack of IP addresses
+	InsufficientAddressCapacityException = 4310
+	// InsufficientCapacityException represents a lack of capacity
+	InsufficientCapacityException = 4315
+	// InsufficientNetworkCapacityException represents a lack of network capacity
+	InsufficientNetworkCapacityException = 4320
+	// InsufficientServerCapacityException represents a lack of server capacity
+	InsufficientServerCapacityException = 4325
+	// InsufficientStorageCapacityException represents a lack of storage capacity
+	InsufficientStorageCapacityException = 4330
+	// InternalErrorException represents an internal error
+	InternalErrorException = 4335
+	// InvalidParameterValueException represents an invalid parameter
+	InvalidParameterValueException = 4340
+	// ManagementServerException represents an error of the management server
+	ManagementServerException = 4345
+	// NetworkRuleConflictException represents a conflicting network rule
+	NetworkRuleConflictException = 4350
+	// PermissionDeniedException represents a lack of permissions
+	PermissionDeniedException = 4355
+	// ResourceAllocationException represents a failed allocation
+	ResourceAllocationException = 4360
+	// ResourceInUseException represents a resource that is busy
+	ResourceInUseException = 4365
+	// ResourceUnavailableException represents an unavailable resource
+	ResourceUnavailableException = 4370
+	// StorageUnavailableException represents an unavailable storage
+	StorageUnavailableException = 4375
+	// UnsupportedServiceException represents an unsupported service
+	UnsupportedServiceException = 4380
+	// VirtualMachineMigrationException represents a failed migration
+	VirtualMachineMigrationException = 4385
+	// AsyncCommandQueued represents a command that was queued
+	AsyncCommandQueued = 4390
+	// RequestLimitException represents too many requests
+	RequestLimitException = 4395
+	// StorageConflictException represents a conflicting storage
+	StorageConflictException = 4400
+	// ServerAPIException represents a generic API error
+	ServerAPIException = 9999
+)
+
+// CSErrorCode represents the CloudStack CSExceptionErrorCode enum
+//
+// See: https://github.com/apache/cloudstack/blob/master/utils/src/main/java/com/cloud/utils/exception/CSExceptionErrorCode.java
+type CSErrorCode int
+
 // JobResultResponse represents a generic response to a job task
 type JobResultResponse struct {
 	AccountID     string           `json:"accountid,omitempty"`
 	Cmd           string           `json:"cmd"`
-	Created       string           `json:"created"`
+	Created       *Timestamp       `json:"created,omitempty"`
 	JobID         string           `json:"jobid"`
 	JobProcStatus int              `json:"jobprocstatus"`
 	JobResult     *json.RawMessage `json:"jobresult"`
@@ -108,10 +178,13 @@ type JobResultResponse struct {
 
 // ErrorResponse represents the standard error response from CloudStack
 type ErrorResponse struct {
-	ErrorCode   ErrorCode  `json:"errorcode"`
-	CsErrorCode int        `json:"cserrorcode"`
-	ErrorText   string     `json:"errortext"`
-	UUIDList    []UUIDItem `json:"uuidList,omitempty"` // uuid*L*ist is not a typo
+	ErrorCode   ErrorCode   `json:"errorcode"`
+	CsErrorCode CSErrorCode `json:"cserrorcode"`
+	ErrorText   string      `json:"errortext"`
+	UUIDList    []UUIDItem  `json:"uuidList,omitempty"` // uuid*L*ist is not a typo
+
+	// kind is set when the error was built by egoscale itself, e.g. ErrNotFound
+	kind error
 }
 
 // UUIDItem represents an item of the UUIDList part of an ErrorResponse
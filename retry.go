@@ -0,0 +1,108 @@
+package egoscale
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RetryPolicy represents how the transient failures of the idempotent commands are retried
+//
+// Only the commands that don't alter anything are retried: the List* ones,
+// queryAsyncJobResult and getVMPassword. The other ones, e.g. deployVirtualMachine,
+// may have been executed by CloudStack even though the response never came back.
+type RetryPolicy struct {
+	// MaxRetries represents how many times a command is retried
+	MaxRetries int
+	// MinDelay represents the time waited before the first retry, 100ms if unset
+	MinDelay time.Duration
+	// MaxDelay caps the time waited between two retries, 10s if unset
+	MaxDelay time.Duration
+	// OnRetry is called before every retry, if set
+	OnRetry RetryFunc
+}
+
+// RetryFunc represents the callback called when a command is retried
+//
+// The attempt starts at one and delay is the time waited before retrying.
+type RetryFunc func(command string, attempt int, err error, delay time.Duration)
+
+const (
+	defaultRetryMinDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay = 10 * time.Second
+)
+
+// NewRetryPolicy creates a policy retrying up to maxRetries times, starting at 100ms, up to 10s
+func NewRetryPolicy(maxRetries int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries: maxRetries,
+		MinDelay:   defaultRetryMinDelay,
+		MaxDelay:   defaultRetryMaxDelay,
+	}
+}
+
+// delay computes the time to wait before the given attempt, starting at one
+//
+// The delay doubles with each attempt and half of it is randomized (jitter) so
+// that many clients failing at once don't retry in lockstep.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d, max := p.MinDelay, p.MaxDelay
+	if d <= 0 {
+		d = defaultRetryMinDelay
+	}
+	if max <= 0 {
+		max = defaultRetryMaxDelay
+	}
+	if max < d {
+		max = d
+	}
+
+	// doubling stops at the cap, a large attempt cannot overflow
+	for i := 1; i < attempt && d < max; i++ {
+		if d > max/2 {
+			d = max
+			break
+		}
+		d *= 2
+	}
+
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// isIdempotent tells whether the command may be sent more than once safely
+func isIdempotent(command string) bool {
+	switch command {
+	case "queryAsyncJobResult", "getVMPassword":
+		return true
+	}
+	return strings.HasPrefix(command, "list")
+}
+
+// HTTPStatusError represents an HTTP error whose body isn't a CloudStack error
+//
+// This happens when something sitting in front of CloudStack, like a load
+// balancer, answers the request.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Error formats the error
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Body)
+}
+
+// Temporary tells whether the HTTP status represents a transient error
+func (e *HTTPStatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
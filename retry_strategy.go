@@ -0,0 +1,105 @@
+package egoscale
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ConstantRetryStrategy waits for the same amount of time between each poll
+func ConstantRetryStrategy(delay time.Duration) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		return delay
+	}
+}
+
+// LinearRetryStrategy waits for initial then adds step at each iteration
+func LinearRetryStrategy(initial, step time.Duration) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		return initial + time.Duration(iteration)*step
+	}
+}
+
+// ExponentialRetryStrategy waits for initial then doubles the delay at each iteration
+//
+// It's meant to be Capped.
+func ExponentialRetryStrategy(initial time.Duration) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		return exponential(initial, iteration)
+	}
+}
+
+// FullJitterRetryStrategy waits for a random time between zero and the exponential delay, up to max
+//
+// See: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func FullJitterRetryStrategy(base, max time.Duration) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		d := exponential(base, iteration)
+		if d > max {
+			d = max
+		}
+		return randomDuration(0, d)
+	}
+}
+
+// DecorrelatedJitterRetryStrategy waits for a random time between base and three times the previous delay, up to max
+//
+// The previous delays are drawn again at each call, which keeps the strategy
+// free of state, hence safe to share between many jobs.
+//
+// See: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func DecorrelatedJitterRetryStrategy(base, max time.Duration) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		d := base
+		for i := int64(0); i <= iteration; i++ {
+			d = randomDuration(base, d*3)
+			if d > max {
+				d = max
+			}
+		}
+		return d
+	}
+}
+
+// Capped caps the delays of the inner strategy to max
+func Capped(max time.Duration, inner RetryStrategyFunc) RetryStrategyFunc {
+	return func(iteration int64) time.Duration {
+		if d := inner(iteration); d < max {
+			return d
+		}
+		return max
+	}
+}
+
+// retryStrategy returns the strategy to poll the given async command
+func (exo *Client) retryStrategy(command string) RetryStrategyFunc {
+	if strategy, ok := exo.RetryStrategies[command]; ok && strategy != nil {
+		return strategy
+	}
+	if exo.RetryStrategy != nil {
+		return exo.RetryStrategy
+	}
+	return FibonacciRetryStrategy
+}
+
+// exponential computes initial * 2^iteration without overflowing
+func exponential(initial time.Duration, iteration int64) time.Duration {
+	d := initial
+	for i := int64(0); i < iteration; i++ {
+		if d > maxDuration/2 {
+			return maxDuration
+		}
+		d *= 2
+	}
+	return d
+}
+
+// maxDuration is the longest time.Duration
+const maxDuration = time.Duration(1<<63 - 1)
+
+// randomDuration returns a random duration in [min, max]
+func randomDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
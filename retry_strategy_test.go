@@ -0,0 +1,107 @@
+package egoscale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConstantRetryStrategy(t *testing.T) {
+	s := ConstantRetryStrategy(time.Second)
+	for i := int64(0); i < 5; i++ {
+		if d := s(i); d != time.Second {
+			t.Errorf("1s was expected, got %v", d)
+		}
+	}
+}
+
+func TestLinearRetryStrategy(t *testing.T) {
+	s := LinearRetryStrategy(time.Second, 500*time.Millisecond)
+	if d := s(0); d != time.Second {
+		t.Errorf("1s was expected, got %v", d)
+	}
+	if d := s(4); d != 3*time.Second {
+		t.Errorf("3s was expected, got %v", d)
+	}
+}
+
+func TestExponentialRetryStrategy(t *testing.T) {
+	s := ExponentialRetryStrategy(time.Second)
+	if d := s(0); d != time.Second {
+		t.Errorf("1s was expected, got %v", d)
+	}
+	if d := s(3); d != 8*time.Second {
+		t.Errorf("8s was expected, got %v", d)
+	}
+	if d := s(1000); d != maxDuration {
+		t.Errorf("the delay should not overflow, got %v", d)
+	}
+}
+
+func TestCapped(t *testing.T) {
+	s := Capped(10*time.Second, ExponentialRetryStrategy(time.Second))
+	if d := s(2); d != 4*time.Second {
+		t.Errorf("4s was expected, got %v", d)
+	}
+	if d := s(10); d != 10*time.Second {
+		t.Errorf("10s was expected, got %v", d)
+	}
+
+	s = Capped(3*time.Second, FibonacciRetryStrategy)
+	if d := s(10); d != 3*time.Second {
+		t.Errorf("3s was expected, got %v", d)
+	}
+}
+
+func TestFullJitterRetryStrategy(t *testing.T) {
+	s := FullJitterRetryStrategy(time.Second, 5*time.Second)
+	for i := 0; i < 100; i++ {
+		if d := s(1); d < 0 || d > 2*time.Second {
+			t.Errorf("a delay between 0s and 2s was expected, got %v", d)
+		}
+		if d := s(10); d < 0 || d > 5*time.Second {
+			t.Errorf("a delay between 0s and 5s was expected, got %v", d)
+		}
+	}
+}
+
+func TestDecorrelatedJitterRetryStrategy(t *testing.T) {
+	s := DecorrelatedJitterRetryStrategy(time.Second, 5*time.Second)
+	for i := 0; i < 100; i++ {
+		if d := s(0); d < time.Second || d > 3*time.Second {
+			t.Errorf("a delay between 1s and 3s was expected, got %v", d)
+		}
+		if d := s(10); d < time.Second || d > 5*time.Second {
+			t.Errorf("a delay between 1s and 5s was expected, got %v", d)
+		}
+	}
+}
+
+func TestRetryStrategies(t *testing.T) {
+	ts := newServer(response{200, `
+{"expungevirtualmachine": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobresult": {
+		"success": true
+	},
+	"jobstatus": 1
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = ConstantRetryStrategy(time.Hour)
+	cs.RetryStrategies = map[string]RetryStrategyFunc{
+		"expungeVirtualMachine": ConstantRetryStrategy(time.Millisecond),
+	}
+
+	if err := cs.BooleanRequest(&ExpungeVirtualMachine{ID: "123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cs.retryStrategy("deployVirtualMachine")(0) != time.Hour {
+		t.Errorf("the default strategy was expected")
+	}
+}
@@ -0,0 +1,183 @@
+package egoscale
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFlakyServer(failures int, response string) (*httptest.Server, *int32) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= int32(failures) {
+			// drop the connection without answering
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(response))
+	})
+	return httptest.NewServer(mux), &calls
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ts, calls := newFlakyServer(2, `{"listzonesresponse": {"count": 0, "zone": []}}`)
+	defer ts.Close()
+
+	attempts := make([]int, 0)
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryPolicy = NewRetryPolicy(3)
+	cs.RetryPolicy.MinDelay = time.Millisecond
+	cs.RetryPolicy.OnRetry = func(command string, attempt int, err error, delay time.Duration) {
+		if command != "listZones" {
+			t.Errorf("bad command, got %q", command)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(calls) != 3 || len(attempts) != 2 {
+		t.Errorf("two retries were expected, got %d calls, %v", atomic.LoadInt32(calls), attempts)
+	}
+}
+
+func TestRetryPolicyClientTimeout(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// slower than the timeout of the client
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"listzonesresponse": {"count": 0, "zone": []}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.HTTPClient.Timeout = 50 * time.Millisecond
+	cs.RetryPolicy = NewRetryPolicy(3)
+	cs.RetryPolicy.MinDelay = time.Millisecond
+
+	if _, err := cs.RequestWithContext(context.Background(), &ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("the timed out call should have been retried once, got %d calls", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestRetryPolicyGiveUp(t *testing.T) {
+	ts := newServer(
+		response{503, "Service Unavailable"},
+		response{503, "Service Unavailable"},
+		response{503, "Service Unavailable"},
+	)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryPolicy = NewRetryPolicy(1)
+	cs.RetryPolicy.MinDelay = time.Millisecond
+
+	_, err := cs.Request(&ListZones{})
+	e, ok := err.(*HTTPStatusError)
+	if !ok || e.StatusCode != 503 {
+		t.Errorf("a 503 HTTPStatusError was expected, got %v", err)
+	}
+}
+
+func TestRetryPolicyServerError(t *testing.T) {
+	ts := newServer(
+		response{530, `{"listzonesresponse": {"errorcode": 530, "cserrorcode": 4370, "errortext": "try again"}}`},
+		response{200, `{"listzonesresponse": {"count": 0, "zone": []}}`},
+	)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryPolicy = NewRetryPolicy(1)
+	cs.RetryPolicy.MinDelay = time.Millisecond
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryPolicyNotIdempotent(t *testing.T) {
+	ts, calls := newFlakyServer(1, `{"deployvirtualmachineresponse": {"jobid": "1", "jobstatus": 1}}`)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryPolicy = NewRetryPolicy(3)
+	cs.RetryPolicy.MinDelay = time.Millisecond
+
+	if _, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+	}); err == nil {
+		t.Errorf("an error was expected")
+	}
+
+	if atomic.LoadInt32(calls) != 1 {
+		t.Errorf("an async command must not be retried, got %d calls", atomic.LoadInt32(calls))
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &RetryPolicy{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for i := 0; i < 10; i++ {
+		if d := p.delay(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Errorf("a delay between 50ms and 100ms was expected, got %v", d)
+		}
+		if d := p.delay(3); d < 200*time.Millisecond || d > 400*time.Millisecond {
+			t.Errorf("a delay between 200ms and 400ms was expected, got %v", d)
+		}
+		if d := p.delay(30); d < 500*time.Millisecond || d > time.Second {
+			t.Errorf("a delay between 500ms and 1s was expected, got %v", d)
+		}
+	}
+}
+
+func TestRetryPolicyDelayDefaults(t *testing.T) {
+	p := &RetryPolicy{MaxRetries: 3}
+
+	for i := 0; i < 10; i++ {
+		if d := p.delay(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Errorf("a delay between 50ms and 100ms was expected, got %v", d)
+		}
+		if d := p.delay(1000); d < 5*time.Second || d > 10*time.Second {
+			t.Errorf("a delay between 5s and 10s was expected, got %v", d)
+		}
+	}
+
+	p = &RetryPolicy{MinDelay: time.Hour, MaxDelay: 1<<63 - 1}
+	if d := p.delay(100); d <= 0 {
+		t.Errorf("a positive delay was expected, got %v", d)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	for _, command := range []string{"listZones", "listVirtualMachines", "queryAsyncJobResult", "getVMPassword"} {
+		if !isIdempotent(command) {
+			t.Errorf("%s is idempotent", command)
+		}
+	}
+	for _, command := range []string{"deployVirtualMachine", "createSSHKeyPair", "associateIpAddress"} {
+		if isIdempotent(command) {
+			t.Errorf("%s is not idempotent", command)
+		}
+	}
+}
@@ -38,25 +38,26 @@ func (sg *SecurityGroup) Get(ctx context.Context, client *Client) error {
 	if sg.ID == "" && sg.Name == "" {
 		return fmt.Errorf("A SecurityGroup may only be searched using ID or Name")
 	}
-	resp, err := client.RequestWithContext(ctx, &ListSecurityGroups{
+	req := &ListSecurityGroups{
 		ID:                sg.ID,
 		SecurityGroupName: sg.Name,
-	})
+	}
 
+	resp, err := client.RequestWithContext(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	sgs := resp.(*ListSecurityGroupsResponse)
+	sgs, ok := resp.(*ListSecurityGroupsResponse)
+	if !ok {
+		return &UnexpectedResponseError{Command: req.APIName(), Response: resp}
+	}
+
 	count := len(sgs.SecurityGroup)
 	if count == 0 {
-		err := &ErrorResponse{
-			ErrorCode: ParamError,
-			ErrorText: fmt.Sprintf("SecurityGroup not found id: %s, name: %s", sg.ID, sg.Name),
-		}
-		return err
+		return notFoundError("SecurityGroup not found id: %s, name: %s", sg.ID, sg.Name)
 	} else if count > 1 {
-		return fmt.Errorf("More than one SecurityGroup was found. Query: id: %s, name: %s", sg.ID, sg.Name)
+		return multipleResultsError("More than one SecurityGroup was found. Query: id: %s, name: %s", sg.ID, sg.Name)
 	}
 
 	return copier.Copy(sg, sgs.SecurityGroup[0])
@@ -161,16 +162,16 @@ func (*DeleteSecurityGroup) response() interface{} {
 // CloudStack API: https://cloudstack.apache.org/api/apidocs-4.10/apis/authorizeSecurityGroupIngress.html
 type AuthorizeSecurityGroupIngress struct {
 	Account               string              `json:"account,omitempty"`
-	CidrList              []string            `json:"cidrlist,omitempty"`
+	CidrList              []string            `json:"cidrlist,omitempty" validate:"cidr"`
 	Description           string              `json:"description,omitempty"`
 	DomainID              string              `json:"domainid,omitempty"`
-	IcmpType              int                 `json:"icmptype,omitempty"`
-	IcmpCode              int                 `json:"icmpcode,omitempty"`
-	StartPort             int                 `json:"startport,omitempty"`
-	EndPort               int                 `json:"endport,omitempty"`
+	IcmpType              int                 `json:"icmptype,omitempty" validate:"min=-1,max=255"`
+	IcmpCode              int                 `json:"icmpcode,omitempty" validate:"min=-1,max=255"`
+	StartPort             int                 `json:"startport,omitempty" validate:"min=0,max=65535"`
+	EndPort               int                 `json:"endport,omitempty" validate:"min=0,max=65535"`
 	ProjectID             string              `json:"projectid,omitempty"`
-	Protocol              string              `json:"protocol,omitempty"`
-	SecurityGroupID       string              `json:"securitygroupid,omitempty"`
+	Protocol              string              `json:"protocol,omitempty" validate:"protocol"`
+	SecurityGroupID       string              `json:"securitygroupid,omitempty" validate:"excludes=SecurityGroupName"`
 	SecurityGroupName     string              `json:"securitygroupname,omitempty"`
 	UserSecurityGroupList []UserSecurityGroup `json:"usersecuritygrouplist,omitempty"`
 }
@@ -1,15 +1,28 @@
 package egoscale
 
 import (
+	"encoding"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// DateFormat is the layout of the CloudStack dates
+const DateFormat = "2006-01-02T15:04:05-0700"
+
+var (
+	ipType            = reflect.TypeOf(net.IP{})
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 func csQuotePlus(s string) string {
@@ -38,7 +51,7 @@ func rawValue(b json.RawMessage) (json.RawMessage, error) {
 func rawValues(b json.RawMessage) (json.RawMessage, error) {
 	var i []json.RawMessage
 
-	if err := json.Unmarshal(b, &i); err != nil {
+	if err := json.Unmarshal(b, &i); err != nil || len(i) == 0 {
 		return nil, nil
 	}
 
@@ -47,17 +60,34 @@ func rawValues(b json.RawMessage) (json.RawMessage, error) {
 
 // prepareValues uses a command to build a POST request
 //
-// command is not a Command so it's easier to Test
-func prepareValues(prefix string, params *url.Values, command interface{}) error {
+// command is not a Command so it's easier to Test. The skipped fields are
+// reported to the logger, if any.
+//
+// Beside the basic types and the pointers to them, the fields may be a
+// time.Time, in the CloudStack date format, or implement
+// encoding.TextMarshaler. A nested struct is serialized like a list of one
+// element: name[0].field=value.
+func prepareValues(prefix string, params *url.Values, command interface{}, logger Logger) error {
 	value := reflect.ValueOf(command)
 	typeof := reflect.TypeOf(command)
 
+	if command == nil {
+		return fmt.Errorf("Cannot serialize a nil value")
+	}
+
 	// Going up the pointer chain to find the underlying struct
 	for typeof.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return fmt.Errorf("Cannot serialize a nil %s", typeof)
+		}
 		typeof = typeof.Elem()
 		value = value.Elem()
 	}
 
+	if typeof.Kind() != reflect.Struct {
+		return fmt.Errorf("Only structs can be serialized, got %s", typeof)
+	}
+
 	for i := 0; i < typeof.NumField(); i++ {
 		field := typeof.Field(i)
 		val := value.Field(i)
@@ -66,6 +96,20 @@ func prepareValues(prefix string, params *url.Values, command interface{}) error
 			n, required := extractJSONTag(field.Name, json)
 			name := prefix + n
 
+			if s, ok, err := textValue(val); ok {
+				if err != nil {
+					return fmt.Errorf("%s.%s (%v) cannot be encoded: %s", typeof.Name(), field.Name, val.Kind(), err)
+				}
+				if s == "" {
+					if required {
+						return fmt.Errorf("%s.%s (%v) is required, got \"\"", typeof.Name(), field.Name, val.Kind())
+					}
+				} else {
+					(*params).Set(name, s)
+				}
+				continue
+			}
+
 			switch val.Kind() {
 			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 				v := val.Int()
@@ -117,12 +161,27 @@ func prepareValues(prefix string, params *url.Values, command interface{}) error
 					if required {
 						return fmt.Errorf("%s.%s (%v) is required, got tempty ptr", typeof.Name(), field.Name, val.Kind())
 					}
+				} else if field.Type.Elem().Kind() == reflect.Struct {
+					if err := prepareValues(name+"[0].", params, val.Interface(), logger); err != nil {
+						return err
+					}
+				} else if s, ok := formatScalar(val.Elem()); ok {
+					(*params).Set(name, s)
 				} else {
-					switch field.Type.Elem().Kind() {
-					case reflect.Bool:
-						params.Set(name, strconv.FormatBool(val.Elem().Bool()))
-					default:
-						log.Printf("[SKIP] %s.%s (%v) not supported", typeof.Name(), field.Name, field.Type.Elem().Kind())
+					skip(logger, typeof, field, fmt.Sprintf("%v not supported", field.Type.Elem().Kind()))
+				}
+			case reflect.Struct:
+				if val.IsZero() {
+					if required {
+						return fmt.Errorf("%s.%s (%v) is required, got empty struct", typeof.Name(), field.Name, val.Kind())
+					}
+				} else {
+					nested := val.Interface()
+					if val.CanAddr() {
+						nested = val.Addr().Interface()
+					}
+					if err := prepareValues(name+"[0].", params, nested, logger); err != nil {
+						return err
 					}
 				}
 			case reflect.Slice:
@@ -157,8 +216,11 @@ func prepareValues(prefix string, params *url.Values, command interface{}) error
 						} else {
 							elems := make([]string, 0, val.Len())
 							for i := 0; i < val.Len(); i++ {
-								// XXX what if the value contains a comma? Double encode?
 								s := val.Index(i).String()
+								if strings.Contains(s, ",") {
+									// CloudStack splits the lists on the commas, without any escaping
+									return fmt.Errorf("%s.%s (%v) cannot contain a comma, got %q", typeof.Name(), field.Name, val.Kind(), s)
+								}
 								elems = append(elems, s)
 							}
 							(*params).Set(name, strings.Join(elems, ","))
@@ -169,8 +231,13 @@ func prepareValues(prefix string, params *url.Values, command interface{}) error
 						if required {
 							return fmt.Errorf("%s.%s (%v) is required, got empty slice", typeof.Name(), field.Name, val.Kind())
 						}
+					} else if s, ok, err := joinScalars(val); ok {
+						if err != nil {
+							return fmt.Errorf("%s.%s (%v) cannot be encoded: %s", typeof.Name(), field.Name, val.Kind(), err)
+						}
+						(*params).Set(name, s)
 					} else {
-						err := prepareList(name, params, val.Interface())
+						err := prepareList(name, params, val.Interface(), logger)
 						if err != nil {
 							return err
 						}
@@ -191,51 +258,207 @@ func prepareValues(prefix string, params *url.Values, command interface{}) error
 				if required {
 					return fmt.Errorf("Unsupported type %s.%s (%v)", typeof.Name(), field.Name, val.Kind())
 				}
+				skip(logger, typeof, field, fmt.Sprintf("%v not supported", val.Kind()))
 			}
 		} else {
-			log.Printf("[SKIP] %s.%s no json label found", typeof.Name(), field.Name)
+			skip(logger, typeof, field, "no json label found")
 		}
 	}
 
 	return nil
 }
 
-func prepareList(prefix string, params *url.Values, slice interface{}) error {
+// skip reports a field that cannot be serialized
+func skip(logger Logger, typeof reflect.Type, field reflect.StructField, reason string) {
+	if logger == nil {
+		return
+	}
+	logger.Log(LevelWarn, "field skipped", Field{"type", typeof.Name()}, Field{"field", field.Name}, Field{"reason", reason})
+}
+
+// prepareList serializes the list of structs, or of maps, as prefix[i].name=value
+func prepareList(prefix string, params *url.Values, slice interface{}, logger Logger) error {
 	value := reflect.ValueOf(slice)
 
 	for i := 0; i < value.Len(); i++ {
-		prepareValues(fmt.Sprintf("%s[%d].", prefix, i), params, value.Index(i).Interface())
+		item := value.Index(i)
+		if item.Kind() == reflect.Map {
+			if err := setMapValues(fmt.Sprintf("%s[%d].", prefix, i), params, item); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := prepareValues(fmt.Sprintf("%s[%d].", prefix, i), params, item.Interface(), logger)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// prepareMap serializes the map as prefix[i].key=value, i following the order of the sorted keys
 func prepareMap(prefix string, params *url.Values, m interface{}) error {
 	value := reflect.ValueOf(m)
+	if value.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("Only maps with string keys are supported, got %s", value.Type())
+	}
 
-	for i, key := range value.MapKeys() {
-		var keyName string
-		var keyValue string
-
-		switch key.Kind() {
-		case reflect.String:
-			keyName = key.String()
-		default:
-			return fmt.Errorf("Only map[string]string are supported (XXX)")
+	i := 0
+	for _, key := range sortedMapKeys(value) {
+		keyValue, ok, err := scalarValue(value.MapIndex(key))
+		if err != nil {
+			return fmt.Errorf("%s.%s cannot be encoded: %s", prefix, key.String(), err)
+		}
+		if !ok {
+			continue
 		}
+		params.Set(fmt.Sprintf("%s[%d].%s", prefix, i, key.String()), keyValue)
+		i++
+	}
+	return nil
+}
 
-		val := value.MapIndex(key)
-		switch val.Kind() {
-		case reflect.String:
-			keyValue = val.String()
-		default:
-			return fmt.Errorf("Only map[string]string are supported (XXX)")
+// sortedMapKeys returns the string keys of the map, sorted
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// setMapValues serializes the map, an item of a list, as prefix.key=value
+func setMapValues(prefix string, params *url.Values, m reflect.Value) error {
+	if m.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("Only maps with string keys are supported, got %s", m.Type())
+	}
+
+	for _, key := range sortedMapKeys(m) {
+		keyValue, ok, err := scalarValue(m.MapIndex(key))
+		if err != nil {
+			return fmt.Errorf("%s%s cannot be encoded: %s", prefix, key.String(), err)
+		}
+		if ok {
+			params.Set(prefix+key.String(), keyValue)
 		}
-		params.Set(fmt.Sprintf("%s[%d].%s", prefix, i, keyName), keyValue)
 	}
 	return nil
 }
 
+// textValue encodes the time.Time in the CloudStack date format, and the encoding.TextMarshaler
+//
+// ok tells whether the value is one of those, a nil one being encoded as "".
+func textValue(val reflect.Value) (s string, ok bool, err error) {
+	t := val.Type()
+	if t == ipType || !val.CanInterface() {
+		return "", false, nil
+	}
+
+	if t == timeType || (t.Kind() == reflect.Ptr && t.Elem() == timeType) {
+		if t.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return "", true, nil
+			}
+			val = val.Elem()
+		}
+		tm := val.Interface().(time.Time)
+		if tm.IsZero() {
+			return "", true, nil
+		}
+		return tm.Format(DateFormat), true, nil
+	}
+
+	var marshaler encoding.TextMarshaler
+	switch {
+	case t.Implements(textMarshalerType):
+		if t.Kind() == reflect.Ptr && val.IsNil() {
+			return "", true, nil
+		}
+		marshaler = val.Interface().(encoding.TextMarshaler)
+	case val.CanAddr() && reflect.PtrTo(t).Implements(textMarshalerType):
+		marshaler = val.Addr().Interface().(encoding.TextMarshaler)
+	default:
+		return "", false, nil
+	}
+
+	b, err := marshaler.MarshalText()
+	return string(b), true, err
+}
+
+// formatScalar encodes the value of a basic type
+func formatScalar(val reflect.Value) (string, bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'f', -1, 64), true
+	case reflect.String:
+		return val.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(val.Bool()), true
+	}
+	return "", false
+}
+
+// scalarValue encodes a single value, e.g. of a map or a list, going through the pointers and interfaces
+//
+// ok is false for a nil value.
+func scalarValue(val reflect.Value) (s string, ok bool, err error) {
+	if val.Type() == ipType {
+		return net.IP(val.Bytes()).String(), true, nil
+	}
+
+	for {
+		if s, ok, err := textValue(val); ok {
+			return s, true, err
+		}
+		if val.Kind() != reflect.Ptr && val.Kind() != reflect.Interface {
+			break
+		}
+		if val.IsNil() {
+			return "", false, nil
+		}
+		val = val.Elem()
+	}
+
+	if s, ok := formatScalar(val); ok {
+		return s, true, nil
+	}
+	return "", false, fmt.Errorf("unsupported type %s", val.Type())
+}
+
+// joinScalars encodes the list of time.Time or TextMarshaler as a comma separated string
+//
+// ok is false for the other lists, e.g. of structs or maps.
+func joinScalars(val reflect.Value) (s string, ok bool, err error) {
+	elem := val.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem != timeType && !elem.Implements(textMarshalerType) && !reflect.PtrTo(elem).Implements(textMarshalerType) {
+		return "", false, nil
+	}
+
+	elems := make([]string, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		s, ok, err := scalarValue(val.Index(i))
+		if err != nil {
+			return "", true, err
+		}
+		if strings.Contains(s, ",") {
+			return "", true, fmt.Errorf("cannot contain a comma, got %q", s)
+		}
+		if ok {
+			elems = append(elems, s)
+		}
+	}
+	return strings.Join(elems, ","), true, nil
+}
+
 // extractJSONTag returns the variable name or defaultName as well as if the field is required (!omitempty)
 func extractJSONTag(defaultName, jsonTag string) (string, bool) {
 	tags := strings.Split(jsonTag, ",")
@@ -252,3 +475,274 @@ func extractJSONTag(defaultName, jsonTag string) (string, bool) {
 	}
 	return name, required
 }
+
+// decodeValues fills the struct with the parameters, it's the inverse of prepareValues
+func decodeValues(prefix string, params url.Values, value reflect.Value) error {
+	typeof := value.Type()
+
+	for i := 0; i < typeof.NumField(); i++ {
+		field := typeof.Field(i)
+		val := value.Field(i)
+		tag, ok := field.Tag.Lookup("json")
+		if !ok || !val.CanSet() {
+			continue
+		}
+
+		n, _ := extractJSONTag(field.Name, tag)
+		name := prefix + n
+
+		switch val.Kind() {
+		case reflect.Struct:
+			if !isText(field.Type) {
+				if err := decodeValues(name+"[0].", params, val); err != nil {
+					return err
+				}
+				continue
+			}
+		case reflect.Ptr:
+			if field.Type.Elem().Kind() == reflect.Struct && !isText(field.Type.Elem()) {
+				if !hasPrefix(params, name+"[0].") {
+					continue
+				}
+				v := reflect.New(field.Type.Elem())
+				if err := decodeValues(name+"[0].", params, v.Elem()); err != nil {
+					return err
+				}
+				val.Set(v)
+				continue
+			}
+		case reflect.Slice:
+			if elem := field.Type.Elem(); elem != ipType && !isText(elem) && (elem.Kind() == reflect.Struct || elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Map) {
+				if err := decodeList(name, params, val); err != nil {
+					return err
+				}
+				continue
+			}
+		case reflect.Map:
+			if err := decodeMap(name, params, val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		v, ok := params[name]
+		if !ok || len(v) == 0 {
+			continue
+		}
+		if err := decodeValue(v[0], val); err != nil {
+			return fmt.Errorf("%s.%s (%v) cannot be decoded: %s", typeof.Name(), field.Name, val.Kind(), err)
+		}
+	}
+
+	return nil
+}
+
+// decodeValue parses the scalar, or list of scalars, into val
+func decodeValue(s string, val reflect.Value) error {
+	if val.Type() == timeType {
+		t, err := time.Parse(DateFormat, s)
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if val.Type() != ipType && val.CanAddr() && reflect.PtrTo(val.Type()).Implements(textUnmarshalerType) {
+		return val.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(s, 10, val.Type().Bits())
+		if err != nil {
+			return err
+		}
+		val.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, val.Type().Bits())
+		if err != nil {
+			return err
+		}
+		val.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(s, val.Type().Bits())
+		if err != nil {
+			return err
+		}
+		val.SetFloat(v)
+	case reflect.String:
+		val.SetString(s)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		val.SetBool(v)
+	case reflect.Ptr:
+		v := reflect.New(val.Type().Elem())
+		if err := decodeValue(s, v.Elem()); err != nil {
+			return err
+		}
+		val.Set(v)
+	case reflect.Slice:
+		switch val.Type().Elem().Kind() {
+		case reflect.Uint8:
+			if val.Type() == reflect.TypeOf(net.IPv4zero) {
+				ip := net.ParseIP(s)
+				if ip == nil {
+					return fmt.Errorf("%q is not a valid IP address", s)
+				}
+				val.SetBytes(ip)
+				return nil
+			}
+			v, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+			val.SetBytes(v)
+		case reflect.String:
+			elems := strings.Split(s, ",")
+			val.Set(reflect.ValueOf(elems).Convert(val.Type()))
+		default:
+			if !isText(val.Type().Elem()) {
+				return fmt.Errorf("unsupported type %s", val.Type())
+			}
+			elems := strings.Split(s, ",")
+			slice := reflect.MakeSlice(val.Type(), len(elems), len(elems))
+			for i, elem := range elems {
+				if err := decodeValue(elem, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			val.Set(slice)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", val.Type())
+	}
+
+	return nil
+}
+
+// decodeList reads the list of structs, or of maps, serialized as prefix[i].name=value
+func decodeList(prefix string, params url.Values, val reflect.Value) error {
+	seen := make(map[int]bool)
+	indexes := make([]int, 0)
+	for k := range params {
+		if i, _, ok := indexedKey(prefix, k); ok && !seen[i] {
+			seen[i] = true
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+
+	elem := val.Type().Elem()
+	slice := reflect.MakeSlice(val.Type(), 0, len(indexes))
+	for _, i := range indexes {
+		if elem.Kind() == reflect.Map {
+			item := reflect.New(elem).Elem()
+			if err := decodeMapItem(fmt.Sprintf("%s[%d].", prefix, i), params, item); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, item)
+			continue
+		}
+
+		item := reflect.New(elem).Elem()
+		target := item
+		if elem.Kind() == reflect.Ptr {
+			item = reflect.New(elem.Elem())
+			target = item.Elem()
+		}
+		if target.Kind() != reflect.Struct {
+			return fmt.Errorf("Only lists of structs can be decoded, got %s", val.Type())
+		}
+		if err := decodeValues(fmt.Sprintf("%s[%d].", prefix, i), params, target); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, item)
+	}
+
+	val.Set(slice)
+	return nil
+}
+
+// decodeMap reads the map serialized as prefix[i].key=value
+//
+// The values of a map[string]interface{} are decoded as strings.
+func decodeMap(prefix string, params url.Values, val reflect.Value) error {
+	if !isStringMap(val.Type()) {
+		return fmt.Errorf("Only map[string]string and map[string]interface{} are supported, got %s", val.Type())
+	}
+
+	m := reflect.MakeMap(val.Type())
+	for k, v := range params {
+		if _, key, ok := indexedKey(prefix, k); ok && len(v) > 0 {
+			m.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(v[0]).Convert(val.Type().Elem()))
+		}
+	}
+
+	if m.Len() > 0 {
+		val.Set(m)
+	}
+	return nil
+}
+
+// decodeMapItem reads the map, an item of a list, serialized as prefix.key=value
+func decodeMapItem(prefix string, params url.Values, val reflect.Value) error {
+	if !isStringMap(val.Type()) {
+		return fmt.Errorf("Only map[string]string and map[string]interface{} are supported, got %s", val.Type())
+	}
+
+	m := reflect.MakeMap(val.Type())
+	for k, v := range params {
+		if strings.HasPrefix(k, prefix) && len(v) > 0 {
+			m.SetMapIndex(reflect.ValueOf(k[len(prefix):]), reflect.ValueOf(v[0]).Convert(val.Type().Elem()))
+		}
+	}
+
+	val.Set(m)
+	return nil
+}
+
+// isStringMap tells whether the map is a map[string]string or a map[string]interface{}
+func isStringMap(t reflect.Type) bool {
+	if t.Key().Kind() != reflect.String {
+		return false
+	}
+	return t.Elem().Kind() == reflect.String || (t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() == 0)
+}
+
+// isText tells whether the type is a time.Time or is encoded as a text of its own
+func isText(t reflect.Type) bool {
+	return t == timeType || t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// hasPrefix tells whether any parameter starts with the prefix
+func hasPrefix(params url.Values, prefix string) bool {
+	for k := range params {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexedKey splits prefix[i].key into its index and key
+func indexedKey(prefix, k string) (int, string, bool) {
+	if !strings.HasPrefix(k, prefix+"[") {
+		return 0, "", false
+	}
+	rest := k[len(prefix)+1:]
+	end := strings.Index(rest, "].")
+	if end < 0 {
+		return 0, "", false
+	}
+	i, err := strconv.Atoi(rest[:end])
+	if err != nil || i < 0 {
+		return 0, "", false
+	}
+	return i, rest[end+2:], true
+}
@@ -3,7 +3,9 @@ package egoscale
 import (
 	"net"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestPrepareValues(t *testing.T) {
@@ -58,7 +60,7 @@ func TestPrepareValues(t *testing.T) {
 	}
 
 	params := url.Values{}
-	err := prepareValues("", &params, profile)
+	err := prepareValues("", &params, profile, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,7 +129,7 @@ func TestPrepareValuesStringRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -139,7 +141,7 @@ func TestPrepareValuesBoolRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err != nil {
 		t.Fatal(nil)
 	}
@@ -154,7 +156,7 @@ func TestPrepareValuesBoolPtrRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -166,7 +168,7 @@ func TestPrepareValuesIntRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -178,7 +180,7 @@ func TestPrepareValuesUintRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -190,7 +192,7 @@ func TestPrepareValuesBytesRequired(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -202,7 +204,7 @@ func TestPrepareValuesSliceString(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -214,7 +216,7 @@ func TestPrepareValuesIP(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -228,7 +230,7 @@ func TestPrepareValuesIPZero(t *testing.T) {
 	}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -240,7 +242,7 @@ func TestPrepareValuesMap(t *testing.T) {
 	}{}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err == nil {
 		t.Errorf("It should have failed")
 	}
@@ -266,7 +268,7 @@ func TestPrepareValuesBoolPtr(t *testing.T) {
 	}
 
 	params := url.Values{}
-	err := prepareValues("", &params, &profile)
+	err := prepareValues("", &params, &profile, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -286,3 +288,346 @@ func TestPrepareValuesBoolPtr(t *testing.T) {
 		t.Errorf("Expected is_five to be missing, got %v", is_five)
 	}
 }
+
+func TestPrepareValuesScalarPointers(t *testing.T) {
+	i, u, f, str := 0, uint16(8080), 2.5, ""
+
+	profile := struct {
+		Int    *int     `json:"int,omitempty"`
+		Uint   *uint16  `json:"uint,omitempty"`
+		Float  *float64 `json:"float,omitempty"`
+		String *string  `json:"string,omitempty"`
+		Nil    *int     `json:"nil,omitempty"`
+	}{
+		Int:    &i,
+		Uint:   &u,
+		Float:  &f,
+		String: &str,
+	}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, &profile, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := url.Values{
+		"int":    {"0"},
+		"uint":   {"8080"},
+		"float":  {"2.5"},
+		"string": {""},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("bad params, got %v", params)
+	}
+}
+
+func TestPrepareValuesTime(t *testing.T) {
+	date := time.Date(2018, 4, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	profile := struct {
+		StartDate time.Time  `json:"startdate"`
+		EndDate   *time.Time `json:"enddate,omitempty"`
+		Since     *time.Time `json:"since,omitempty"`
+	}{
+		StartDate: date,
+		EndDate:   &date,
+	}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, profile, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := url.Values{
+		"startdate": {"2018-04-01T12:30:00+0200"},
+		"enddate":   {"2018-04-01T12:30:00+0200"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("bad params, got %v", params)
+	}
+
+	empty := struct {
+		StartDate time.Time `json:"startdate"`
+	}{}
+	if err := prepareValues("", &url.Values{}, empty, nil); err == nil {
+		t.Errorf("an error was expected for the required zero time")
+	}
+}
+
+type testCIDR struct {
+	net.IPNet
+}
+
+func (c testCIDR) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *testCIDR) UnmarshalText(text []byte) error {
+	_, n, err := net.ParseCIDR(string(text))
+	if err != nil {
+		return err
+	}
+	c.IPNet = *n
+	return nil
+}
+
+func mustParseCIDR(s string) testCIDR {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return testCIDR{*n}
+}
+
+func TestPrepareValuesTextMarshaler(t *testing.T) {
+	cidr := mustParseCIDR("10.0.0.0/24")
+
+	profile := struct {
+		CIDR     testCIDR   `json:"cidr"`
+		CIDRPtr  *testCIDR  `json:"cidrptr,omitempty"`
+		CIDRNil  *testCIDR  `json:"cidrnil,omitempty"`
+		CIDRList []testCIDR `json:"cidrlist,omitempty"`
+		IPs      []net.IP   `json:"ips,omitempty"`
+	}{
+		CIDR:     cidr,
+		CIDRPtr:  &cidr,
+		CIDRList: []testCIDR{cidr, mustParseCIDR("::/0")},
+		IPs:      []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+	}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, &profile, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := url.Values{
+		"cidr":     {"10.0.0.0/24"},
+		"cidrptr":  {"10.0.0.0/24"},
+		"cidrlist": {"10.0.0.0/24,::/0"},
+		"ips":      {"192.0.2.1,2001:db8::1"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("bad params, got %v", params)
+	}
+}
+
+func TestPrepareValuesNested(t *testing.T) {
+	type rule struct {
+		Protocol string `json:"protocol"`
+		Port     *int   `json:"port,omitempty"`
+	}
+
+	port := 22
+	profile := struct {
+		Rule     rule                     `json:"rule"`
+		RulePtr  *rule                    `json:"ruleptr,omitempty"`
+		Empty    rule                     `json:"empty,omitempty"`
+		Details  map[string]interface{}   `json:"details,omitempty"`
+		Networks []map[string]string      `json:"networks,omitempty"`
+		Options  []map[string]interface{} `json:"options,omitempty"`
+	}{
+		Rule:     rule{Protocol: "tcp", Port: &port},
+		RulePtr:  &rule{Protocol: "udp"},
+		Details:  map[string]interface{}{"cpu": 2},
+		Networks: []map[string]string{{"networkid": "1"}, {"networkid": "2"}},
+		Options:  []map[string]interface{}{{"enabled": true}},
+	}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, &profile, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := url.Values{
+		"rule[0].protocol":      {"tcp"},
+		"rule[0].port":          {"22"},
+		"ruleptr[0].protocol":   {"udp"},
+		"details[0].cpu":        {"2"},
+		"networks[0].networkid": {"1"},
+		"networks[1].networkid": {"2"},
+		"options[0].enabled":    {"true"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("bad params, got %v", params)
+	}
+
+	required := struct {
+		Rule rule `json:"rule"`
+	}{}
+	if err := prepareValues("", &url.Values{}, required, nil); err == nil {
+		t.Errorf("an error was expected for the required empty struct")
+	}
+
+	unsupported := struct {
+		Details map[string]interface{} `json:"details"`
+	}{
+		Details: map[string]interface{}{"nested": []int{1}},
+	}
+	if err := prepareValues("", &url.Values{}, unsupported, nil); err == nil {
+		t.Errorf("an error was expected for the unsupported map value")
+	}
+}
+
+func TestDecodeValuesRoundTrip(t *testing.T) {
+	type rule struct {
+		Protocol string `json:"protocol"`
+		Port     *int   `json:"port,omitempty"`
+	}
+
+	port := 22
+	date := time.Date(2018, 4, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60))
+	type profile struct {
+		Date     time.Time           `json:"date"`
+		CIDR     *testCIDR           `json:"cidr,omitempty"`
+		CIDRList []testCIDR          `json:"cidrlist,omitempty"`
+		Rule     rule                `json:"rule"`
+		RulePtr  *rule               `json:"ruleptr,omitempty"`
+		Networks []map[string]string `json:"networks,omitempty"`
+	}
+
+	cidr := mustParseCIDR("10.0.0.0/24")
+	p := &profile{
+		Date:     date,
+		CIDR:     &cidr,
+		CIDRList: []testCIDR{cidr},
+		Rule:     rule{Protocol: "tcp", Port: &port},
+		RulePtr:  &rule{Protocol: "udp"},
+		Networks: []map[string]string{{"networkid": "1"}, {"networkid": "2"}},
+	}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &profile{}
+	if err := decodeValues("", params, reflect.ValueOf(decoded).Elem()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.Date.Equal(date) {
+		t.Errorf("bad date, got %v", decoded.Date)
+	}
+	decoded.Date = p.Date
+	if !reflect.DeepEqual(p, decoded) {
+		t.Errorf("bad profile, got %#v", decoded)
+	}
+}
+
+func TestPrepareValuesWireFormat(t *testing.T) {
+	tests := []struct {
+		command  Command
+		expected string
+	}{
+		{
+			&DeployVirtualMachine{
+				ServiceOfferingID:  "1",
+				TemplateID:         "2",
+				ZoneID:             "3",
+				SecurityGroupNames: []string{"default", "web"},
+				Details: map[string]string{
+					"keyboard": "fr-ch",
+					"cpu":      "2",
+					"memory":   "4096",
+					"disk":     "50",
+				},
+			},
+			"command=deployVirtualMachine" +
+				"&details[0].cpu=2" +
+				"&details[1].disk=50" +
+				"&details[2].keyboard=fr-ch" +
+				"&details[3].memory=4096" +
+				"&securitygroupnames=default%2Cweb" +
+				"&serviceofferingid=1&templateid=2&zoneid=3",
+		},
+		{
+			&CreateTags{
+				ResourceIDs:  []string{"1", "2"},
+				ResourceType: "UserVm",
+				Tags: []ResourceTag{
+					{Key: "env", Value: "prod"},
+					{Key: "app", Value: "web server"},
+				},
+			},
+			"command=createTags" +
+				"&resourceids=1%2C2" +
+				"&resourcetype=UserVm" +
+				"&tags[0].key=env&tags[0].value=prod" +
+				"&tags[1].key=app&tags[1].value=web%20server",
+		},
+		{
+			&AuthorizeSecurityGroupIngress{
+				SecurityGroupName: "web",
+				Protocol:          "tcp",
+				StartPort:         80,
+				EndPort:           443,
+				CidrList:          []string{"0.0.0.0/0", "::/0"},
+				UserSecurityGroupList: []UserSecurityGroup{
+					{Group: "default", Account: "test"},
+				},
+			},
+			"cidrlist=0.0.0.0%2F0%2C%3A%3A%2F0" +
+				"&command=authorizeSecurityGroupIngress" +
+				"&endport=443&protocol=tcp&securitygroupname=web&startport=80" +
+				"&usersecuritygrouplist[0].account=test" +
+				"&usersecuritygrouplist[0].group=default",
+		},
+	}
+
+	for _, test := range tests {
+		// the maps are iterated in a random order, the output must not depend on it
+		for i := 0; i < 20; i++ {
+			params := url.Values{}
+			if err := prepareValues("", &params, test.command, nil); err != nil {
+				t.Fatal(err)
+			}
+			params.Set("command", test.command.APIName())
+
+			if got := encodeValues(params); got != test.expected {
+				t.Fatalf("%s: bad wire format\n got: %s\nwant: %s", test.command.APIName(), got, test.expected)
+			}
+		}
+	}
+}
+
+func TestPrepareValuesComma(t *testing.T) {
+	tests := []interface{}{
+		&DeployVirtualMachine{
+			ServiceOfferingID:  "1",
+			TemplateID:         "2",
+			ZoneID:             "3",
+			SecurityGroupNames: []string{"default", "web,db"},
+		},
+		struct {
+			Names []commaName `json:"names"`
+		}{
+			Names: []commaName{"a,b"},
+		},
+	}
+
+	for _, test := range tests {
+		if err := prepareValues("", &url.Values{}, test, nil); err == nil {
+			t.Errorf("an error was expected for %#v", test)
+		}
+	}
+
+	// a comma is fine outside of a list
+	params := url.Values{}
+	err := prepareValues("", &params, &CreateTags{
+		ResourceIDs:  []string{"1"},
+		ResourceType: "UserVm",
+		Tags:         []ResourceTag{{Key: "owners", Value: "alice,bob"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := params.Get("tags[0].value"); v != "alice,bob" {
+		t.Errorf("bad tag value, got %q", v)
+	}
+}
+
+type commaName string
+
+func (n commaName) MarshalText() ([]byte, error) {
+	return []byte(n), nil
+}
@@ -9,7 +9,7 @@ type ServiceOffering struct {
 	ID                        string            `json:"id"`
 	CPUNumber                 int               `json:"cpunumber"`
 	CPUSpeed                  int               `json:"cpuspeed"`
-	Created                   string            `json:"created"`
+	Created                   *Timestamp        `json:"created,omitempty"`
 	DefaultUse                bool              `json:"defaultuse,omitempty"`
 	DeploymentPlanner         string            `json:"deploymentplanner,omitempty"`
 	DiskBytesReadRate         int64             `json:"diskBytesReadRate,omitempty"`
@@ -0,0 +1,124 @@
+package egoscale
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	params := url.Values{}
+	params.Set("apikey", "KEY")
+	params.Set("command", "listZones")
+	params.Set("name", "ch gva+2")
+	params.Set("response", "json")
+	params.Set("signature", Sign(params, "SECRET"))
+
+	if err := VerifySignature(params, "SECRET"); err != nil {
+		t.Errorf("the signature should be valid, got %v", err)
+	}
+
+	if err := VerifySignature(params, "WRONG"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ErrInvalidSignature was expected, got %v", err)
+	}
+
+	params.Set("name", "de-fra-1")
+	if err := VerifySignature(params, "SECRET"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ErrInvalidSignature was expected, got %v", err)
+	}
+
+	params.Del("signature")
+	if err := VerifySignature(params, "SECRET"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ErrInvalidSignature was expected, got %v", err)
+	}
+}
+
+func TestVerifySignatureExpires(t *testing.T) {
+	tests := []struct {
+		expires string
+		err     error
+	}{
+		{time.Now().Add(time.Minute).Format(ExpiresFormat), nil},
+		{time.Now().Add(-time.Minute).Format(ExpiresFormat), ErrSignatureExpired},
+		{"tomorrow", ErrInvalidSignature},
+		{"", ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+		params := url.Values{}
+		params.Set("apikey", "KEY")
+		params.Set("command", "listZones")
+		params.Set("signatureversion", "3")
+		params.Set("expires", test.expires)
+		params.Set("signature", Sign(params, "SECRET"))
+
+		err := VerifySignature(params, "SECRET")
+		if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+			t.Errorf("%q: %v was expected, got %v", test.expires, test.err, err)
+		}
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	var query url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if r.Method != "GET" || VerifySignature(query, "SECRET") != nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"listzonesresponse": {}}`))
+	}))
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Expiration = 10 * time.Minute
+
+	u, err := cs.SignedURL(&ListZones{Name: "ch-gva-2", Keyword: "a b,c[0]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, ts.URL+"?") {
+		t.Errorf("the URL should start with the endpoint, got %q", u)
+	}
+
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("the signature should be valid, got %d", resp.StatusCode)
+	}
+	if query.Get("command") != "listZones" || query.Get("apikey") != "KEY" || query.Get("signatureversion") != "3" {
+		t.Errorf("bad query, got %v", query)
+	}
+}
+
+func TestRequestExpiration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("expires") == "" || VerifySignature(r.PostForm, "SECRET") != nil {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"listzonesresponse": {"errorcode": 401, "errortext": "unable to verify user credentials"}}`))
+			return
+		}
+		w.Write([]byte(`{"listzonesresponse": {}}`))
+	}))
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	if _, err := cs.Request(&ListZones{}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("ErrUnauthorized was expected without expires, got %v", err)
+	}
+
+	cs.Expiration = time.Minute
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,7 +4,7 @@ package egoscale
 type Snapshot struct {
 	ID           string        `json:"id"`
 	Account      string        `json:"account"`
-	Created      string        `json:"created,omitempty"`
+	Created      *Timestamp    `json:"created,omitempty"`
 	Domain       string        `json:"domain"`
 	DomainID     string        `json:"domainid"`
 	IntervalType string        `json:"intervaltype,omitempty"` // hourly, daily, weekly, monthly, ..., none
@@ -0,0 +1,206 @@
+package egoscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// StrictMode tells how the client reacts to the responses not fitting their structs
+type StrictMode int
+
+const (
+	// StrictOff ignores the unknown fields, like encoding/json does
+	StrictOff StrictMode = iota
+	// StrictLog reports the unknown fields and the type mismatches to the Logger, at LevelWarn
+	StrictLog
+	// StrictFail fails the requests with a DecodeError, e.g. in the tests
+	StrictFail
+)
+
+// DecodeError represents a response not fitting its struct
+//
+// The fields are named after the JSON keys, e.g. virtualmachine[0].nic[1].foo
+type DecodeError struct {
+	Command string
+	Fields  []FieldError
+}
+
+// Error formats the error
+func (e *DecodeError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		reasons[i] = f.Field + " " + f.Reason
+	}
+	return fmt.Sprintf("The response of %s doesn't fit: %s", e.Command, strings.Join(reasons, "; "))
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(json.RawMessage{})
+)
+
+// strict checks the body against the response, according to the Strict mode of the client
+func (exo *Client) strict(command string, body []byte, response interface{}) error {
+	if exo.Strict == StrictOff || len(body) == 0 || response == nil {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+
+	fields := checkJSON("", reflect.TypeOf(response), data)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	e := &DecodeError{Command: command, Fields: fields}
+	if exo.Strict == StrictFail {
+		return e
+	}
+
+	for _, f := range fields {
+		exo.log(LevelWarn, "response drift", Field{"command", command}, Field{"field", f.Field}, Field{"reason", f.Reason})
+	}
+	return nil
+}
+
+// checkJSON lists the values of the decoded JSON which don't fit the type
+func checkJSON(path string, t reflect.Type, data interface{}) []FieldError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if data == nil || t == rawMessageType || t.Kind() == reflect.Interface {
+		return nil
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		// it decodes itself
+		return nil
+	}
+
+	var fields []FieldError
+	mismatch := func(got string) []FieldError {
+		name := path
+		if name == "" {
+			name = "response"
+		}
+		return []FieldError{{Field: name, Reason: fmt.Sprintf("expected %s, got %s", t, got)}}
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			known := jsonFields(t)
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				ft, ok := known[strings.ToLower(key)]
+				if !ok {
+					fields = append(fields, FieldError{Field: joinPath(path, key), Reason: "is unknown"})
+					continue
+				}
+				fields = append(fields, checkJSON(joinPath(path, key), ft, v[key])...)
+			}
+		case reflect.Map:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				fields = append(fields, checkJSON(joinPath(path, key), t.Elem(), v[key])...)
+			}
+		default:
+			return mismatch("an object")
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return mismatch("an array")
+		}
+		for i, item := range v {
+			fields = append(fields, checkJSON(fmt.Sprintf("%s[%d]", path, i), t.Elem(), item)...)
+		}
+	case string:
+		if t.Kind() == reflect.String || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+			return nil
+		}
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// base64
+			return nil
+		}
+		return mismatch("a string")
+	case float64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return nil
+		}
+		return mismatch("a number")
+	case bool:
+		if t.Kind() != reflect.Bool {
+			return mismatch("a boolean")
+		}
+	}
+
+	return fields
+}
+
+// jsonFields returns the types of the fields of the struct, by lowercase JSON name
+//
+// Like encoding/json, the fields of the embedded structs are promoted.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+// joinPath appends the key to the path of a JSON value
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,139 @@
+package egoscale
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestStrictFail(t *testing.T) {
+	ts := newServer(response{200, `
+{"listzonesresponse": {
+	"count": 1,
+	"zone": [
+		{
+			"id": "1",
+			"name": "ch-gva-2",
+			"newfield": "surprise",
+			"tags": [{"key": "env", "value": "prod", "color": "red"}]
+		}
+	]
+}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Strict = StrictFail
+
+	_, err := cs.Request(&ListZones{})
+
+	var e *DecodeError
+	if !errors.As(err, &e) {
+		t.Fatalf("a DecodeError was expected, got %v", err)
+	}
+	if e.Command != "listZones" {
+		t.Errorf("bad command, got %q", e.Command)
+	}
+
+	expected := []string{"zone[0].newfield", "zone[0].tags[0].color"}
+	if len(e.Fields) != len(expected) {
+		t.Fatalf("%d fields were expected, got %v", len(expected), err)
+	}
+	for i, field := range expected {
+		if e.Fields[i].Field != field || e.Fields[i].Reason != "is unknown" {
+			t.Errorf("the unknown field %s was expected, got %v", field, e.Fields[i])
+		}
+	}
+}
+
+func TestStrictTypeMismatch(t *testing.T) {
+	ts := newServer(response{200, `{"listzonesresponse": {"count": "one", "zone": [{"id": "1", "name": 2}]}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Strict = StrictFail
+
+	_, err := cs.Request(&ListZones{})
+
+	var e *DecodeError
+	if !errors.As(err, &e) {
+		t.Fatalf("a DecodeError was expected, got %v", err)
+	}
+
+	expected := "The response of listZones doesn't fit: count expected int, got a string; zone[0].name expected string, got a number"
+	if err.Error() != expected {
+		t.Errorf("bad error\n got: %s\nwant: %s", err, expected)
+	}
+}
+
+func TestStrictAsyncResultError(t *testing.T) {
+	ts := newServer(
+		response{200, `{"deployvirtualmachineresponse": {"jobid": "1", "jobstatus": 0}}`},
+		response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 1,
+	"jobresult": {"virtualmachine": {"id": 2, "gpu": "none"}}
+}}`},
+	)
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Strict = StrictFail
+
+	_, err := cs.Request(&DeployVirtualMachine{ServiceOfferingID: "1", TemplateID: "2", ZoneID: "3"})
+
+	var e *json.UnmarshalTypeError
+	if !errors.As(err, &e) {
+		t.Errorf("the error of the result was expected first, got %v", err)
+	}
+}
+
+func TestStrictLog(t *testing.T) {
+	ts := newServer(
+		response{200, `{"listzonesresponse": {"count": 1, "zone": [{"id": "1", "newfield": true}]}}`},
+		response{200, `{"deployvirtualmachineresponse": {"jobid": "1", "jobstatus": 0}}`},
+		response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 1,
+	"jobresultcode": 0,
+	"jobresulttype": "object",
+	"jobresult": {"virtualmachine": {"id": "2", "gpu": "none"}}
+}}`},
+	)
+	defer ts.Close()
+
+	logger := &recordingLogger{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.Logger = logger
+	cs.Strict = StrictLog
+
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cs.Request(&DeployVirtualMachine{ServiceOfferingID: "1", TemplateID: "2", ZoneID: "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	drifts := make([]string, 0)
+	for _, entry := range logger.entries {
+		if entry.msg == "response drift" && entry.level == LevelWarn {
+			drifts = append(drifts, entry.fields["field"].(string))
+		}
+	}
+
+	if len(drifts) != 2 || drifts[0] != "zone[0].newfield" || drifts[1] != "virtualmachine.gpu" {
+		t.Errorf("the unknown fields should have been logged, got %v", drifts)
+	}
+}
+
+func TestStrictOff(t *testing.T) {
+	ts := newServer(response{200, `{"listzonesresponse": {"count": 1, "zone": [{"id": "1", "newfield": true}]}}`})
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	if _, err := cs.Request(&ListZones{}); err != nil {
+		t.Errorf("the unknown fields should be ignored by default, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package egoscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timestamp represents a date of the API, e.g. 2018-04-01T12:30:00+0200
+//
+// It reads the CloudStack format, RFC 3339 as used by the DNS API, as well as
+// the "2006-01-02 15:04:05" and "2006-01-02" layouts. It's written in the
+// CloudStack format. The zero Timestamp is empty: omitted from the command
+// parameters and null in JSON.
+//
+// The dates of the commands and of the responses are all *Timestamp, nil when
+// missing.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampFormats are the layouts a Timestamp is parsed from, in order
+var timestampFormats = []string{
+	DateFormat,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTimestamp reads the date in any of the formats of the API
+func ParseTimestamp(s string) (Timestamp, error) {
+	for _, layout := range timestampFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Timestamp{t}, nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("%q is not a valid timestamp", s)
+}
+
+// String formats the timestamp in the CloudStack format, the zero one being ""
+func (t Timestamp) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateFormat)
+}
+
+// MarshalText formats the timestamp in the CloudStack format
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText reads the timestamp in any of the formats of the API
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*t = Timestamp{}
+		return nil
+	}
+
+	ts, err := ParseTimestamp(string(text))
+	if err != nil {
+		return err
+	}
+	*t = ts
+	return nil
+}
+
+// MarshalJSON formats the timestamp as a JSON string, or null
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON reads the timestamp from a JSON string, or null
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(s))
+}
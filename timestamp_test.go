@@ -0,0 +1,133 @@
+package egoscale
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"2018-04-01T12:30:00+0200", time.Date(2018, 4, 1, 10, 30, 0, 0, time.UTC)},
+		{"2018-04-01T12:30:00-0100", time.Date(2018, 4, 1, 13, 30, 0, 0, time.UTC)},
+		{"2018-04-01T10:30:00Z", time.Date(2018, 4, 1, 10, 30, 0, 0, time.UTC)},
+		{"2018-04-01T12:30:00.123+02:00", time.Date(2018, 4, 1, 10, 30, 0, 123000000, time.UTC)},
+		{"2018-04-01 10:30:00", time.Date(2018, 4, 1, 10, 30, 0, 0, time.UTC)},
+		{"2018-04-01", time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		ts, err := ParseTimestamp(test.s)
+		if err != nil {
+			t.Errorf("%s: %s", test.s, err)
+			continue
+		}
+		if !ts.Equal(test.expected) {
+			t.Errorf("%s: %v was expected, got %v", test.s, test.expected, ts)
+		}
+	}
+
+	if _, err := ParseTimestamp("yesterday"); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestTimestampJSON(t *testing.T) {
+	var vm VirtualMachine
+	if err := json.Unmarshal([]byte(`{"id": "1", "created": "2018-04-01T12:30:00+0200"}`), &vm); err != nil {
+		t.Fatal(err)
+	}
+	if vm.Created == nil || !vm.Created.Equal(time.Date(2018, 4, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("bad created date, got %v", vm.Created)
+	}
+
+	b, err := json.Marshal(struct {
+		Created Timestamp  `json:"created"`
+		Removed Timestamp  `json:"removed"`
+		Expires *Timestamp `json:"expires,omitempty"`
+	}{
+		Created: *vm.Created,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"created":"2018-04-01T12:30:00+0200","removed":null}` {
+		t.Errorf("bad JSON, got %s", b)
+	}
+
+	var ts Timestamp
+	if err := json.Unmarshal([]byte(`null`), &ts); err != nil || !ts.IsZero() {
+		t.Errorf("a zero timestamp was expected, got %v (%v)", ts, err)
+	}
+	if err := json.Unmarshal([]byte(`""`), &ts); err != nil || !ts.IsZero() {
+		t.Errorf("a zero timestamp was expected, got %v (%v)", ts, err)
+	}
+	if err := json.Unmarshal([]byte(`42`), &ts); err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestTimestampParams(t *testing.T) {
+	date := Timestamp{time.Date(2018, 4, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60))}
+
+	params := url.Values{}
+	if err := prepareValues("", &params, &ListAsyncJobs{StartDate: &date}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := params.Get("startdate"); v != "2018-04-01T12:30:00+0200" {
+		t.Errorf("bad startdate, got %q", v)
+	}
+
+	params = url.Values{}
+	if err := prepareValues("", &params, &ListAsyncJobs{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := params["startdate"]; ok {
+		t.Errorf("the zero startdate shouldn't be set, got %q", params.Get("startdate"))
+	}
+
+	params, err := commandValues(&ListEvents{StartDate: &date}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := params.Get("startdate"); v != "2018-04-01 12:30:00" {
+		t.Errorf("bad startdate, got %q", v)
+	}
+	if _, ok := params["enddate"]; ok {
+		t.Errorf("the zero enddate shouldn't be set, got %q", params.Get("enddate"))
+	}
+
+	decoded, err := DecodeCommand(url.Values{"command": {"listAsyncJobs"}, "startdate": {"2018-04-01T12:30:00+0200"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs := decoded.(*ListAsyncJobs); jobs.StartDate == nil || !jobs.StartDate.Equal(date.Time) {
+		t.Errorf("bad startdate, got %v", jobs.StartDate)
+	}
+}
+
+func TestDNSDomainTimestamps(t *testing.T) {
+	var domain DNSDomain
+	if err := json.Unmarshal([]byte(`{"id": 1, "name": "example.net", "created_at": "2018-04-01T10:30:00Z"}`), &domain); err != nil {
+		t.Fatal(err)
+	}
+	if domain.CreatedAt == nil || !domain.CreatedAt.Equal(time.Date(2018, 4, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("bad created_at date, got %v", domain.CreatedAt)
+	}
+	if domain.UpdatedAt != nil {
+		t.Errorf("no updated_at date was expected, got %v", domain.UpdatedAt)
+	}
+
+	b, err := json.Marshal(DNSDomain{Name: "example.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "_at") {
+		t.Errorf("the missing dates should be omitted, got %s", b)
+	}
+}
@@ -0,0 +1,65 @@
+package egoscale
+
+import (
+	"context"
+)
+
+// Tracer opens the spans around the API calls
+//
+// It's meant to be a thin adapter over a tracing library, e.g. OpenTelemetry.
+// A span is opened per logical operation, like a Request, a Paginate or the
+// Wait of an async job, with a child span for every HTTP call made.
+type Tracer interface {
+	// Start opens a span, child of the span carried by ctx if any, and returns the context carrying it
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents an operation being traced
+//
+// The attributes set by the client are command, page, jobid and jobstatus,
+// and status for the HTTP calls.
+type Span interface {
+	// SetAttribute sets the attribute of the span
+	SetAttribute(key string, value interface{})
+	// RecordError records the failure of the operation
+	RecordError(err error)
+	// End closes the span
+	End()
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// nopSpan is the span used when there is no Tracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) RecordError(err error)                      {}
+func (nopSpan) End()                                       {}
+
+// startSpan opens a span using the Tracer of the client, if any, with the command attribute
+func (exo *Client) startSpan(ctx context.Context, name, command string) (context.Context, Span) {
+	if exo.Tracer == nil {
+		return ctx, nopSpan{}
+	}
+
+	ctx, span := exo.Tracer.Start(ctx, name)
+	span.SetAttribute("command", command)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// spanFromContext returns the span opened by the client and carried by ctx
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return nopSpan{}
+}
+
+// endSpan records the error, if any, and closes the span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
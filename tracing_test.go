@@ -0,0 +1,134 @@
+package egoscale
+
+import (
+	"context"
+	"testing"
+)
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.err = err }
+func (s *testSpan) End()                                       { s.ended = true }
+
+type testSpanKey struct{}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracerAsyncJob(t *testing.T) {
+	ts := newServer(response{200, `
+{"deployvirtualmachineresponse": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 0
+}}`}, response{200, `
+{"queryasyncjobresultresponse": {
+	"jobid": "1",
+	"jobstatus": 1,
+	"jobresult": {"virtualmachine": {"id": "123"}}
+}}`})
+	defer ts.Close()
+
+	tracer := &testTracer{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.RetryStrategy = ConstantRetryStrategy(0)
+	cs.Tracer = tracer
+
+	_, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID: "1",
+		TemplateID:        "2",
+		ZoneID:            "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 5 {
+		t.Fatalf("5 spans were expected, got %d", len(tracer.spans))
+	}
+
+	request, deploy, wait := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if request.name != "egoscale.Request" || request.parent != nil || request.attrs["jobid"] != "1" || request.attrs["jobstatus"] != Success {
+		t.Errorf("bad request span, got %#v", request)
+	}
+	if deploy.name != "egoscale.http" || deploy.parent != request || deploy.attrs["command"] != "deployVirtualMachine" {
+		t.Errorf("bad http span, got %#v", deploy)
+	}
+	if wait.name != "egoscale.Wait" || wait.parent != request || wait.attrs["jobstatus"] != Success {
+		t.Errorf("bad wait span, got %#v", wait)
+	}
+	for _, poll := range tracer.spans[3:] {
+		if poll.parent != wait || poll.attrs["command"] != "queryAsyncJobResult" || poll.attrs["jobid"] != "1" {
+			t.Errorf("bad poll span, got %#v", poll)
+		}
+	}
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Errorf("the span %s should have been ended", span.name)
+		}
+	}
+}
+
+func TestTracerPaginate(t *testing.T) {
+	ts := newServer(response{200, `
+{"listzonesresponse": {
+	"count": 2,
+	"zone": [{"id": "1", "name": "ch-gva-2"}]
+}}`}, response{200, `
+{"listzonesresponse": {
+	"count": 2,
+	"zone": [{"id": "2", "name": "ch-dk-2"}]
+}}`}, response{200, `
+{"listzonesresponse": {}}`})
+	defer ts.Close()
+
+	tracer := &testTracer{}
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+	cs.PageSize = 1
+	cs.Tracer = tracer
+
+	zones, err := cs.List(&Zone{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("two zones were expected, got %d", len(zones))
+	}
+
+	paginate := tracer.spans[0]
+	if paginate.name != "egoscale.Paginate" || paginate.attrs["command"] != "listZones" || paginate.attrs["page"] != 3 {
+		t.Errorf("bad paginate span, got %#v", paginate)
+	}
+
+	pages := []string{}
+	for _, span := range tracer.spans {
+		if span.name != "egoscale.http" {
+			continue
+		}
+		if span.parent == nil || span.parent.parent != paginate {
+			t.Errorf("the HTTP calls should be within the paginate span, got %#v", span)
+		}
+		pages = append(pages, span.attrs["page"].(string))
+	}
+	if len(pages) != 3 || pages[0] != "1" || pages[2] != "3" {
+		t.Errorf("three pages were expected, got %v", pages)
+	}
+}
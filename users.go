@@ -2,22 +2,22 @@ package egoscale
 
 // User represents a User
 type User struct {
-	Account             string `json:"account,omitempty"`
-	AccountID           string `json:"accountid,omitempty"`
-	AccountType         string `json:"accounttype,omitempty"`
-	APIKey              string `json:"apikey,omitempty"`
-	Created             string `json:"created,omitempty"`
-	Domain              string `json:"domain,omitempty"`
-	DomainID            string `json:"domainid,omitempty"`
-	Email               string `json:"email,omitempty"`
-	FirstName           string `json:"firstname,omitempty"`
-	ID                  string `json:"id,omitempty"`
-	IsCallerChildDomain bool   `json:"iscallerchilddomain,omitempty"`
-	IsDefault           bool   `json:"isdefault,omitempty"`
-	LastName            string `json:"lastname,omitempty"`
-	SecretKey           string `json:"secretkey,omitempty"`
-	State               string `json:"state,omitempty"`
-	UserName            string `json:"username,omitempty"`
+	Account             string     `json:"account,omitempty"`
+	AccountID           string     `json:"accountid,omitempty"`
+	AccountType         string     `json:"accounttype,omitempty"`
+	APIKey              string     `json:"apikey,omitempty"`
+	Created             *Timestamp `json:"created,omitempty"`
+	Domain              string     `json:"domain,omitempty"`
+	DomainID            string     `json:"domainid,omitempty"`
+	Email               string     `json:"email,omitempty"`
+	FirstName           string     `json:"firstname,omitempty"`
+	ID                  string     `json:"id,omitempty"`
+	IsCallerChildDomain bool       `json:"iscallerchilddomain,omitempty"`
+	IsDefault           bool       `json:"isdefault,omitempty"`
+	LastName            string     `json:"lastname,omitempty"`
+	SecretKey           string     `json:"secretkey,omitempty"`
+	State               string     `json:"state,omitempty"`
+	UserName            string     `json:"username,omitempty"`
 }
 
 // RegisterUserKeys registers a new set of key of the given user
@@ -0,0 +1,270 @@
+package egoscale
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError represents a field breaking one of its validation rules
+type FieldError struct {
+	// Field is the name of the field, e.g. Tags[0].Key
+	Field  string
+	Reason string
+}
+
+// ValidationError represents a command breaking the validation rules of its fields
+type ValidationError struct {
+	Command string
+	Fields  []FieldError
+}
+
+// Error formats the error
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		reasons[i] = f.Field + " " + f.Reason
+	}
+	return fmt.Sprintf("Invalid %s: %s", e.Command, strings.Join(reasons, "; "))
+}
+
+// Is tells whether the target is ErrInvalidParameter
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidParameter
+}
+
+// Validate checks the fields of the command against the rules of their validate tag
+//
+// The rules are separated by commas:
+//
+//	required        the field cannot be empty
+//	oneof=a b c     the value is one of those, case insensitively
+//	excludes=Other  the field and the Other field cannot be both set
+//	min=n, max=n    the number, or the length of a string or a slice, is within the bounds
+//	cidr            the value, or each value of a slice, is a CIDR
+//	ip, ipv4, ipv6  the value, or each value of a slice, is an IP address
+//	protocol        the value is an IP protocol, by name or by number (0-255)
+//
+// Apart from required, the rules don't apply to the empty fields. The
+// nested structs, and slices of structs, are validated as well.
+//
+//	type AuthorizeSecurityGroupIngress struct {
+//		Protocol  string   `json:"protocol,omitempty" validate:"protocol"`
+//		StartPort int      `json:"startport,omitempty" validate:"min=0,max=65535"`
+//		CidrList  []string `json:"cidrlist,omitempty" validate:"cidr"`
+//		// ...
+//	}
+func Validate(cmd interface{}) error {
+	val := reflect.ValueOf(cmd)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return ErrNilCommand
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	e := &ValidationError{Command: val.Type().Name()}
+	validateStruct("", val, e)
+	if len(e.Fields) > 0 {
+		return e
+	}
+	return nil
+}
+
+// validateStruct checks the fields of the struct, and of the nested ones
+func validateStruct(prefix string, val reflect.Value, e *ValidationError) {
+	typeof := val.Type()
+	for i := 0; i < typeof.NumField(); i++ {
+		field := typeof.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		value := val.Field(i)
+		name := prefix + field.Name
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				if reason := checkRule(strings.TrimSpace(rule), val, value); reason != "" {
+					e.Fields = append(e.Fields, FieldError{Field: name, Reason: reason})
+				}
+			}
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			validateStruct(name+".", value, e)
+		case reflect.Ptr:
+			if !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+				validateStruct(name+".", value.Elem(), e)
+			}
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.Struct {
+				for j := 0; j < value.Len(); j++ {
+					validateStruct(fmt.Sprintf("%s[%d].", name, j), value.Index(j), e)
+				}
+			}
+		}
+	}
+}
+
+// checkRule checks the value against the rule, it returns why it doesn't comply
+func checkRule(rule string, parent, value reflect.Value) string {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+
+	if name == "required" {
+		if isEmptyValue(value) {
+			return "is required"
+		}
+		return ""
+	}
+
+	if isEmptyValue(value) {
+		return ""
+	}
+	value = reflect.Indirect(value)
+
+	switch name {
+	case "excludes":
+		other := parent.FieldByName(arg)
+		if !other.IsValid() {
+			return fmt.Sprintf("excludes the unknown field %s", arg)
+		}
+		if !isEmptyValue(other) {
+			return fmt.Sprintf("is mutually exclusive with %s", arg)
+		}
+	case "oneof":
+		choices := strings.Fields(arg)
+		s := fmt.Sprint(value.Interface())
+		for _, choice := range choices {
+			if strings.EqualFold(s, choice) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(choices, ", "), s)
+	case "min", "max":
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("has an invalid rule %q", rule)
+		}
+		n, ok := measure(value)
+		if !ok {
+			return fmt.Sprintf("cannot be checked against %q, got %v", rule, value.Kind())
+		}
+		if name == "min" && n < bound {
+			return fmt.Sprintf("must be at least %s, got %s", arg, formatNumber(n))
+		}
+		if name == "max" && n > bound {
+			return fmt.Sprintf("must be at most %s, got %s", arg, formatNumber(n))
+		}
+	case "cidr":
+		for _, s := range stringValues(value) {
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return fmt.Sprintf("must be a CIDR, got %q", s)
+			}
+		}
+	case "ip", "ipv4", "ipv6":
+		if ip, ok := value.Interface().(net.IP); ok {
+			if !isIP(name, ip) {
+				return fmt.Sprintf("must be an %s address, got %q", ipKinds[name], ip.String())
+			}
+			return ""
+		}
+		for _, s := range stringValues(value) {
+			if !isIP(name, net.ParseIP(s)) {
+				return fmt.Sprintf("must be an %s address, got %q", ipKinds[name], s)
+			}
+		}
+	case "protocol":
+		s := fmt.Sprint(value.Interface())
+		if n, err := strconv.Atoi(s); err == nil {
+			if n < 0 || n > 255 {
+				return fmt.Sprintf("must be a protocol number between 0 and 255, got %d", n)
+			}
+			return ""
+		}
+		for _, protocol := range protocolNames {
+			if strings.EqualFold(s, protocol) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be a number or one of %s, got %q", strings.Join(protocolNames, ", "), s)
+	default:
+		return fmt.Sprintf("has an unknown rule %q", name)
+	}
+
+	return ""
+}
+
+// isEmptyValue tells whether the field is unset, an empty slice or map being unset
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// measure returns the number, or the length, to check the bounds against
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// formatNumber formats the measured number
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// stringValues returns the string, or the strings of the slice
+func stringValues(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}
+	case reflect.Slice:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, fmt.Sprint(reflect.Indirect(v.Index(i)).Interface()))
+		}
+		return values
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// protocolNames are the IP protocols known by name
+var protocolNames = []string{"tcp", "udp", "icmp", "icmpv6", "ah", "esp", "gre", "ipip", "all"}
+
+// ipKinds are the names of the IP rules in the error messages
+var ipKinds = map[string]string{"ip": "IP", "ipv4": "IPv4", "ipv6": "IPv6"}
+
+// isIP tells whether the IP address is of the given kind: ip, ipv4 or ipv6
+func isIP(kind string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	switch kind {
+	case "ipv4":
+		return ip.To4() != nil
+	case "ipv6":
+		return ip.To4() == nil && ip.To16() != nil
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package egoscale
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []Command{
+		&DeployVirtualMachine{
+			ServiceOfferingID: "1",
+			TemplateID:        "2",
+			ZoneID:            "3",
+			SecurityGroupIDs:  []string{"4"},
+			IPAddress:         net.ParseIP("192.0.2.1"),
+			IP6Address:        net.ParseIP("2001:db8::1"),
+		},
+		&AuthorizeSecurityGroupIngress{
+			SecurityGroupName: "web",
+			Protocol:          "TCP",
+			StartPort:         80,
+			EndPort:           80,
+			CidrList:          []string{"0.0.0.0/0", "::/0"},
+		},
+		&AuthorizeSecurityGroupIngress{
+			SecurityGroupName: "web",
+			Protocol:          "ICMP",
+			IcmpType:          -1,
+			IcmpCode:          -1,
+		},
+		&AuthorizeSecurityGroupIngress{
+			SecurityGroupName: "web",
+			Protocol:          "132",
+			CidrList:          []string{"0.0.0.0/0"},
+		},
+		&CreateNetwork{
+			NetworkOfferingID: "1",
+			ZoneID:            "2",
+			ACLType:           "Account",
+			StartIP:           net.ParseIP("10.0.0.10"),
+			EndIP:             net.ParseIP("10.0.0.20"),
+			IP6Cidr:           "2001:db8::/64",
+		},
+	}
+
+	for _, cmd := range valid {
+		if err := Validate(cmd); err != nil {
+			t.Errorf("%s: no errors were expected, got %v", cmd.APIName(), err)
+		}
+	}
+}
+
+func TestValidateFailure(t *testing.T) {
+	tests := []struct {
+		cmd    Command
+		fields []string
+	}{
+		{&DeployVirtualMachine{
+			AffinityGroupIDs:   []string{"1"},
+			AffinityGroupNames: []string{"default"},
+			SecurityGroupIDs:   []string{"2"},
+			SecurityGroupNames: []string{"default"},
+		}, []string{"AffinityGroupIDs", "SecurityGroupIDs"}},
+		{&DeployVirtualMachine{
+			IPAddress:  net.ParseIP("2001:db8::1"),
+			IP6Address: net.ParseIP("192.0.2.1"),
+		}, []string{"IPAddress", "IP6Address"}},
+		{&AuthorizeSecurityGroupIngress{
+			Protocol:  "sctp",
+			StartPort: 80,
+			EndPort:   65536,
+			CidrList:  []string{"0.0.0.0/0", "10.0.0.1"},
+		}, []string{"CidrList", "EndPort", "Protocol"}},
+		{&AuthorizeSecurityGroupEgress{
+			SecurityGroupID:   "1",
+			SecurityGroupName: "web",
+			IcmpType:          256,
+		}, []string{"IcmpType", "SecurityGroupID"}},
+		{&AuthorizeSecurityGroupIngress{
+			Protocol: "256",
+		}, []string{"Protocol"}},
+		{&CreateNetwork{
+			ACLType: "Project",
+			IP6Cidr: "2001:db8::",
+		}, []string{"ACLType", "IP6Cidr"}},
+		{&DeleteAffinityGroup{ID: "1", Name: "default"}, []string{"ID"}},
+	}
+
+	for _, test := range tests {
+		err := Validate(test.cmd)
+
+		var e *ValidationError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: a ValidationError was expected, got %v", test.cmd.APIName(), err)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("%s: the error should match ErrInvalidParameter", test.cmd.APIName())
+		}
+
+		if len(e.Fields) != len(test.fields) {
+			t.Errorf("%s: %d invalid fields were expected, got %v", test.cmd.APIName(), len(test.fields), err)
+			continue
+		}
+		for i, field := range test.fields {
+			if e.Fields[i].Field != field {
+				t.Errorf("%s: the field %s was expected, got %s", test.cmd.APIName(), field, e.Fields[i].Field)
+			}
+		}
+	}
+}
+
+type validatedCommand struct {
+	Name  string `json:"name,omitempty" validate:"required,max=8"`
+	Rules []struct {
+		Kind string `json:"kind" validate:"oneof=ingress egress"`
+	} `json:"rules,omitempty" validate:"min=1"`
+}
+
+func (validatedCommand) APIName() string {
+	return "validated"
+}
+
+func (validatedCommand) response() interface{} {
+	return new(booleanSyncResponse)
+}
+
+func TestValidateNested(t *testing.T) {
+	cmd := &validatedCommand{}
+	cmd.Rules = append(cmd.Rules, struct {
+		Kind string `json:"kind" validate:"oneof=ingress egress"`
+	}{Kind: "sideways"})
+
+	err := Validate(cmd)
+	expected := `Invalid validatedCommand: Name is required; Rules[0].Kind must be one of ingress, egress, got "sideways"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("bad error, got %v", err)
+	}
+
+	cmd.Name = "much too long"
+	cmd.Rules[0].Kind = "egress"
+	err = Validate(cmd)
+	expected = `Invalid validatedCommand: Name must be at most 8, got 13`
+	if err == nil || err.Error() != expected {
+		t.Errorf("bad error, got %v", err)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	_, err := cs.Request(&DeployVirtualMachine{
+		ServiceOfferingID:  "1",
+		TemplateID:         "2",
+		ZoneID:             "3",
+		AffinityGroupIDs:   []string{"1"},
+		AffinityGroupNames: []string{"default"},
+	})
+	if !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("a validation error was expected, got %v", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("the invalid command shouldn't have been sent, got %d calls", calls)
+	}
+}
+
+type hookedCommand struct {
+	Name string `json:"name"`
+}
+
+func (hookedCommand) APIName() string {
+	return "hooked"
+}
+
+func (hookedCommand) response() interface{} {
+	return new(booleanSyncResponse)
+}
+
+func (hookedCommand) onBeforeSend(params *url.Values) error {
+	return errors.New("Refused by the hook")
+}
+
+func TestRequestOnBeforeSendError(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	cs := NewClient(ts.URL, "KEY", "SECRET")
+
+	if err := cs.BooleanRequest(&hookedCommand{Name: "test"}); err == nil || err.Error() != "Refused by the hook" {
+		t.Errorf("the error of the hook was expected, got %v", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("the command shouldn't have been sent, got %d calls", calls)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"net/url"
 
 	"github.com/jinzhu/copier"
 )
@@ -27,12 +26,9 @@ func (vm *VirtualMachine) Get(ctx context.Context, client *Client) error {
 
 	count := len(vms)
 	if count == 0 {
-		return &ErrorResponse{
-			ErrorCode: ParamError,
-			ErrorText: fmt.Sprintf("VirtualMachine not found. id: %s, name: %s", vm.ID, vm.Name),
-		}
+		return notFoundError("VirtualMachine not found. id: %s, name: %s", vm.ID, vm.Name)
 	} else if count > 1 {
-		return fmt.Errorf("More than one VirtualMachine was found. Query: id: %s, name: %s", vm.ID, vm.Name)
+		return multipleResultsError("More than one VirtualMachine was found. Query: id: %s, name: %s", vm.ID, vm.Name)
 	}
 
 	return copier.Copy(vm, vms[0])
@@ -136,20 +132,6 @@ func (*DeployVirtualMachine) APIName() string {
 	return "deployVirtualMachine"
 }
 
-func (req *DeployVirtualMachine) onBeforeSend(params *url.Values) error {
-	// Either AffinityGroupIDs or AffinityGroupNames must be set
-	if len(req.AffinityGroupIDs) > 0 && len(req.AffinityGroupNames) > 0 {
-		return fmt.Errorf("Either AffinityGroupIDs or AffinityGroupNames must be set")
-	}
-
-	// Either SecurityGroupIDs or SecurityGroupNames must be set
-	if len(req.SecurityGroupIDs) > 0 && len(req.SecurityGroupNames) > 0 {
-		return fmt.Errorf("Either SecurityGroupIDs or SecurityGroupNames must be set")
-	}
-
-	return nil
-}
-
 func (*DeployVirtualMachine) asyncResponse() interface{} {
 	return new(DeployVirtualMachineResponse)
 }
@@ -291,8 +273,13 @@ func (ls *ListVirtualMachines) SetPageSize(pageSize int) {
 	ls.PageSize = pageSize
 }
 
-func (*ListVirtualMachines) each(resp interface{}, callback IterateItemFunc) {
-	vms := resp.(*ListVirtualMachinesResponse)
+func (ls *ListVirtualMachines) each(resp interface{}, callback IterateItemFunc) {
+	vms, ok := resp.(*ListVirtualMachinesResponse)
+	if !ok {
+		callback(nil, &UnexpectedResponseError{Command: ls.APIName(), Response: resp})
+		return
+	}
+
 	for _, vm := range vms.VirtualMachine {
 		if !callback(vm, nil) {
 			break
@@ -2,7 +2,6 @@ package egoscale
 
 import (
 	"net"
-	"net/url"
 	"testing"
 )
 
@@ -149,48 +148,44 @@ func TestUpdateDefaultNicForVirtualMachine(t *testing.T) {
 	_ = req.asyncResponse().(*UpdateDefaultNicForVirtualMachineResponse)
 }
 
-func TestDeployOnBeforeSend(t *testing.T) {
+func TestDeployValidate(t *testing.T) {
 	req := &DeployVirtualMachine{
 		SecurityGroupNames: []string{"default"},
 	}
-	params := new(url.Values)
 
-	if err := req.onBeforeSend(params); err != nil {
+	if err := Validate(req); err != nil {
 		t.Error(err)
 	}
 }
 
-func TestDeployOnBeforeSendNoSG(t *testing.T) {
+func TestDeployValidateNoSG(t *testing.T) {
 	req := &DeployVirtualMachine{}
-	params := new(url.Values)
 
 	// CS will pick the default oiine
-	if err := req.onBeforeSend(params); err != nil {
+	if err := Validate(req); err != nil {
 		t.Error(err)
 	}
 }
 
-func TestDeployOnBeforeSendBothSG(t *testing.T) {
+func TestDeployValidateBothSG(t *testing.T) {
 	req := &DeployVirtualMachine{
 		SecurityGroupIDs:   []string{"1"},
 		SecurityGroupNames: []string{"foo"},
 	}
-	params := new(url.Values)
 
-	if err := req.onBeforeSend(params); err == nil {
+	if err := Validate(req); err == nil {
 		t.Errorf("DeployVM should only accept SG ids or names")
 	}
 }
 
-func TestDeployOnBeforeSendBothAG(t *testing.T) {
+func TestDeployValidateBothAG(t *testing.T) {
 	req := &DeployVirtualMachine{
 		AffinityGroupIDs:   []string{"2"},
 		AffinityGroupNames: []string{"foo"},
 	}
-	params := new(url.Values)
 
-	if err := req.onBeforeSend(params); err == nil {
-		t.Errorf("DeployVM should only accept SG ids or names")
+	if err := Validate(req); err == nil {
+		t.Errorf("DeployVM should only accept AG ids or names")
 	}
 }
 
@@ -15,7 +15,7 @@ type VirtualMachine struct {
 	CPUNumber             int64             `json:"cpunumber,omitempty"`
 	CPUSpeed              int64             `json:"cpuspeed,omitempty"`
 	CPUUsed               string            `json:"cpuused,omitempty"`
-	Created               string            `json:"created,omitempty"`
+	Created               *Timestamp        `json:"created,omitempty"`
 	Details               map[string]string `json:"details,omitempty"`
 	DiskIoRead            int64             `json:"diskioread,omitempty"`
 	DiskIoWrite           int64             `json:"diskiowrite,omitempty"`
@@ -108,10 +108,10 @@ type DeployVirtualMachine struct {
 	TemplateID         string            `json:"templateid"`
 	ZoneID             string            `json:"zoneid"`
 	Account            string            `json:"account,omitempty"`
-	AffinityGroupIDs   []string          `json:"affinitygroupids,omitempty"`   // mutually exclusive with AffinityGroupNames
-	AffinityGroupNames []string          `json:"affinitygroupnames,omitempty"` // mutually exclusive with AffinityGroupIDs
-	CustomID           string            `json:"customid,omitempty"`           // root only
-	DeploymentPlanner  string            `json:"deploymentplanner,omitempty"`  // root only
+	AffinityGroupIDs   []string          `json:"affinitygroupids,omitempty" validate:"excludes=AffinityGroupNames"` // mutually exclusive with AffinityGroupNames
+	AffinityGroupNames []string          `json:"affinitygroupnames,omitempty"`                                      // mutually exclusive with AffinityGroupIDs
+	CustomID           string            `json:"customid,omitempty"`                                                // root only
+	DeploymentPlanner  string            `json:"deploymentplanner,omitempty"`                                       // root only
 	Details            map[string]string `json:"details,omitempty"`
 	DiskOfferingID     string            `json:"diskofferingid,omitempty"`
 	DisplayName        string            `json:"displayname,omitempty"`
@@ -122,18 +122,18 @@ type DeployVirtualMachine struct {
 	Hypervisor         string            `json:"hypervisor,omitempty"`
 	IP4                *bool             `json:"ip4,omitempty"` // Exoscale specific
 	IP6                *bool             `json:"ip6,omitempty"` // Exoscale specific
-	IPAddress          net.IP            `json:"ipaddress,omitempty"`
-	IP6Address         net.IP            `json:"ip6address,omitempty"`
+	IPAddress          net.IP            `json:"ipaddress,omitempty" validate:"ipv4"`
+	IP6Address         net.IP            `json:"ip6address,omitempty" validate:"ipv6"`
 	IPToNetworkList    []IPToNetwork     `json:"iptonetworklist,omitempty"`
 	Keyboard           string            `json:"keyboard,omitempty"`
 	KeyPair            string            `json:"keypair,omitempty"`
 	Name               string            `json:"name,omitempty"`
-	NetworkIDs         []string          `json:"networkids,omitempty"` // mutually exclusive with IPToNetworkList
+	NetworkIDs         []string          `json:"networkids,omitempty" validate:"excludes=IPToNetworkList"` // mutually exclusive with IPToNetworkList
 	ProjectID          string            `json:"projectid,omitempty"`
-	RootDiskSize       int64             `json:"rootdisksize,omitempty"`       // in GiB
-	SecurityGroupIDs   []string          `json:"securitygroupids,omitempty"`   // mutually exclusive with SecurityGroupNames
-	SecurityGroupNames []string          `json:"securitygroupnames,omitempty"` // mutually exclusive with SecurityGroupIDs
-	Size               string            `json:"size,omitempty"`               // mutually exclusive with DiskOfferingID
+	RootDiskSize       int64             `json:"rootdisksize,omitempty"`                                            // in GiB
+	SecurityGroupIDs   []string          `json:"securitygroupids,omitempty" validate:"excludes=SecurityGroupNames"` // mutually exclusive with SecurityGroupNames
+	SecurityGroupNames []string          `json:"securitygroupnames,omitempty"`                                      // mutually exclusive with SecurityGroupIDs
+	Size               string            `json:"size,omitempty" validate:"excludes=DiskOfferingID"`                 // mutually exclusive with DiskOfferingID
 	StartVM            *bool             `json:"startvm,omitempty"`
 	UserData           string            `json:"userdata,omitempty"` // the client is responsible to base64/gzip it
 }
@@ -277,8 +277,8 @@ type ListVirtualMachines struct {
 	HostID            string        `json:"hostid,omitempty"`
 	Hypervisor        string        `json:"hypervisor,omitempty"`
 	ID                string        `json:"id,omitempty"`
-	IDs               []string      `json:"ids,omitempty"` // mutually exclusive with id
-	IPAddress         net.IP        `json:"ipaddress,omitempty"`
+	IDs               []string      `json:"ids,omitempty" validate:"excludes=ID"` // mutually exclusive with id
+	IPAddress         net.IP        `json:"ipaddress,omitempty" validate:"ip"`
 	IsoID             string        `json:"isoid,omitempty"`
 	IsRecursive       *bool         `json:"isrecursive,omitempty"`
 	KeyPair           string        `json:"keypair,omitempty"` // not implemented at Exoscale
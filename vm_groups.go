@@ -2,14 +2,14 @@ package egoscale
 
 // InstanceGroup represents a group of VM
 type InstanceGroup struct {
-	ID        string `json:"id"`
-	Account   string `json:"account,omitempty"`
-	Created   string `json:"created,omitempty"`
-	Domain    string `json:"domain,omitempty"`
-	DomainID  string `json:"domainid,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Project   string `json:"project,omitempty"`
-	ProjectID string `json:"projectid,omitempty"`
+	ID        string     `json:"id"`
+	Account   string     `json:"account,omitempty"`
+	Created   *Timestamp `json:"created,omitempty"`
+	Domain    string     `json:"domain,omitempty"`
+	DomainID  string     `json:"domainid,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Project   string     `json:"project,omitempty"`
+	ProjectID string     `json:"projectid,omitempty"`
 }
 
 // InstanceGroupResponse represents a VM group
@@ -6,7 +6,7 @@ type Volume struct {
 	Account                    string        `json:"account,omitempty"`
 	Attached                   string        `json:"attached,omitempty"`
 	ChainInfo                  string        `json:"chaininfo,omitempty"`
-	Created                    string        `json:"created,omitempty"`
+	Created                    *Timestamp    `json:"created,omitempty"`
 	Destroyed                  bool          `json:"destroyed,omitempty"`
 	DisplayVolume              bool          `json:"displayvolume,omitempty"`
 	Domain                     string        `json:"domain,omitempty"`
@@ -116,8 +116,13 @@ func (ls *ListVolumes) SetPageSize(pageSize int) {
 	ls.PageSize = pageSize
 }
 
-func (*ListVolumes) each(resp interface{}, callback IterateItemFunc) {
-	volumes := resp.(*ListVolumesResponse)
+func (ls *ListVolumes) each(resp interface{}, callback IterateItemFunc) {
+	volumes, ok := resp.(*ListVolumesResponse)
+	if !ok {
+		callback(nil, &UnexpectedResponseError{Command: ls.APIName(), Response: resp})
+		return
+	}
+
 	for _, volume := range volumes.Volume {
 		if !callback(volume, nil) {
 			break
@@ -30,8 +30,13 @@ func (ls *ListZones) SetPageSize(pageSize int) {
 	ls.PageSize = pageSize
 }
 
-func (*ListZones) each(resp interface{}, callback IterateItemFunc) {
-	zones := resp.(*ListZonesResponse)
+func (ls *ListZones) each(resp interface{}, callback IterateItemFunc) {
+	zones, ok := resp.(*ListZonesResponse)
+	if !ok {
+		callback(nil, &UnexpectedResponseError{Command: ls.APIName(), Response: resp})
+		return
+	}
+
 	for _, zone := range zones.Zone {
 		if !callback(zone, nil) {
 			break